@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	// The scripts ask the binary itself for candidates on every <Tab>, so
+	// session names and projects are always current. A flag being typed is
+	// passed as a bare "-", which urfave/cli answers with the flags of the
+	// command; the shell filters them. %[1]s is the command name and %[2]s
+	// the command name usable as a shell function name.
+	completionScripts = map[string]string{
+		"bash": `_%[2]s_complete() {
+  local cur="${COMP_WORDS[COMP_CWORD]}"
+  local -a words=("${COMP_WORDS[@]:0:COMP_CWORD}")
+  if [[ "$cur" == -* ]]; then
+    words+=("-")
+  fi
+  local IFS=$'\n'
+  COMPREPLY=($(compgen -W "$("${words[@]}" --generate-shell-completion 2>/dev/null)" -- "$cur"))
+}
+complete -o default -F _%[2]s_complete %[1]s
+`,
+		"zsh": `#compdef %[1]s
+
+_%[2]s_complete() {
+  local -a args candidates
+  local c
+  args=("${words[@]:0:$((CURRENT-1))}")
+  if [[ "${words[CURRENT]}" == -* ]]; then
+    args+=("-")
+  fi
+  for c in "${(@f)$("${args[@]}" --generate-shell-completion 2>/dev/null)}"; do
+    # flags come as --flag:usage under zsh
+    [[ "$c" == -* ]] && c="${c%%:*}"
+    [[ -n "$c" ]] && candidates+=("$c")
+  done
+  compadd -a candidates
+}
+
+compdef _%[2]s_complete %[1]s
+`,
+		"fish": `function __%[2]s_complete
+    set -l args (commandline -opc)
+    set -l cur (commandline -ct)
+    if string match -q -- '-*' $cur
+        set -a args -
+    end
+    $args --generate-shell-completion 2>/dev/null
+end
+
+complete -c %[1]s -f -a '(__%[2]s_complete)'
+`,
+	}
+)
+
+func newCompletionCmd() *cli.Command {
+	shells := make([]string, 0, len(completionScripts))
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	slices.Sort(shells)
+
+	return &cli.Command{
+		Name:      "completion",
+		Usage:     "print the shell completion script",
+		ArgsUsage: strings.Join(shells, "|"),
+		Description: "Load it from your shell's rc file, e.g.\n" +
+			"  bash: source <(" + CommandName + " completion bash)\n" +
+			"  zsh:  source <(" + CommandName + " completion zsh)\n" +
+			"  fish: " + CommandName + " completion fish | source",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			a, err := args(cmd, 1, 1)
+			if err != nil {
+				return err
+			}
+			script, ok := completionScripts[a[0]]
+			if !ok {
+				return fmt.Errorf("unsupported shell %q, expected one of %s:%w", a[0], cmd.ArgsUsage, ErrInvalidArgs)
+			}
+			name := cmd.Root().Name
+			_, err = fmt.Fprintf(cmd.Root().Writer, script, name, strings.ReplaceAll(name, "-", "_"))
+			return err
+		},
+		ShellComplete: func(ctx context.Context, cmd *cli.Command) {
+			if cmd.NArg() == 0 {
+				printCandidates(cmd, shells)
+			}
+		},
+	}
+}
+
+// completeSessions completes the first argument of rename with running
+// session names and registered project paths.
+func (s *sessionizer) completeSessions(ctx context.Context, cmd *cli.Command) {
+	s.complete(ctx, cmd, 1, func(config *iohelper.Config) []string {
+		candidates := []string{}
+		if sessions, err := tmux.NewTmux().GatherExistingSessions(ctx); err == nil {
+			for _, running := range sessions {
+				candidates = append(candidates, running.Name.Value())
+			}
+		}
+		slices.Sort(candidates)
+		return append(candidates, projectPaths(config)...)
+	})
+}
+
+// completeProjects completes the repository argument of worktree with
+// registered project paths.
+func (s *sessionizer) completeProjects(ctx context.Context, cmd *cli.Command) {
+	s.complete(ctx, cmd, 1, projectPaths)
+}
+
+func (s *sessionizer) completeWorkspaces(ctx context.Context, cmd *cli.Command) {
+	s.complete(ctx, cmd, 1, func(config *iohelper.Config) []string {
+		names := make([]string, 0, len(config.Workspaces))
+		for name := range config.Workspaces {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return names
+	})
+}
+
+// complete prints the candidates for the next argument as long as fewer than
+// maxArgs are given, after the subcommands of cmd. A flag being typed is
+// left to the default completion, which lists the flags. Completion must never fail loudly, so an
+// unreadable config only means no candidates.
+func (s *sessionizer) complete(
+	ctx context.Context,
+	cmd *cli.Command,
+	maxArgs int,
+	candidates func(config *iohelper.Config) []string,
+) {
+	if a := cmd.Args().Slice(); len(a) > 0 && strings.HasPrefix(a[len(a)-1], "-") {
+		cli.DefaultCompleteWithFlags(ctx, cmd)
+		return
+	}
+	if cmd.NArg() >= maxArgs {
+		return
+	}
+	if cmd.NArg() == 0 {
+		for _, sub := range cmd.Commands {
+			// urfave/cli gives every command a help subcommand; it only adds noise here.
+			if !sub.Hidden && sub.Name != "help" {
+				printCandidates(cmd, []string{sub.Name})
+			}
+		}
+	}
+
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return
+	}
+	config, err := s.readConfig(ctx, cmd, configFileAbs)
+	if err != nil {
+		return
+	}
+	printCandidates(cmd, candidates(config))
+}
+
+func projectPaths(config *iohelper.Config) []string {
+	paths := make([]string, 0, len(config.Projects))
+	for _, project := range config.Projects {
+		paths = append(paths, project.Value())
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func printCandidates(cmd *cli.Command, candidates []string) {
+	for _, c := range candidates {
+		fmt.Fprintln(cmd.Root().Writer, c)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCmd_Completion_PrintsScript(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out, err := runTestCmd(t, configFileAbs, "completion", shell)
+		if err != nil {
+			t.Fatalf("completion %s: expected no error, got %v", shell, err)
+		}
+		if !strings.Contains(out, "_tmux_sessionizer_complete") || !strings.Contains(out, "--generate-shell-completion") {
+			t.Errorf("completion %s: unexpected script:\n%s", shell, out)
+		}
+	}
+}
+
+func TestCmd_Completion_RejectsUnknownShell(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+
+	_, err := runTestCmd(t, configFileAbs, "completion", "tcsh")
+
+	if !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+}
+
+func TestCmd_ShellComplete(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, project := range []string{"web", "api"} {
+		if err := os.Mkdir(filepath.Join(root, project), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix+root+"\n"+
+		iohelper.WorkspacePrefix+"shop,"+filepath.Join(root, "web")+"\n"+
+		iohelper.WorkspacePrefix+"backend,"+filepath.Join(root, "api")+"\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "workspace names",
+			args: []string{"workspace"},
+			want: []string{"backend", "shop"},
+		},
+		{
+			name: "worktree subcommands and projects",
+			args: []string{"worktree"},
+			want: []string{"remove", filepath.Join(root, "api"), filepath.Join(root, "web")},
+		},
+		{
+			name: "nothing once the repository is given",
+			args: []string{"worktree", filepath.Join(root, "api")},
+			want: []string{},
+		},
+		{
+			name: "worktree remove projects",
+			args: []string{"worktree", "remove"},
+			want: []string{filepath.Join(root, "api"), filepath.Join(root, "web")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, err := runTestCmd(t, configFileAbs, append(tt.args, "--generate-shell-completion")...)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			got := strings.Fields(out)
+			if got == nil {
+				got = []string{}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("completion mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/TlexCypher/my-tmux-sessionizer/handler"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/daemon"
 	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
@@ -23,24 +25,25 @@ const (
 	ExitCodeError
 )
 
-const (
-	configFile = "~/.tmux-sessionizer"
-)
-
 const (
 	CommandName  = "tmux-sessionizer"
 	CommandUsage = "tmux session manager"
 )
 
 var (
-	ErrNoSuchCmd = errors.New("no such command")
+	ErrNoSuchCmd     = errors.New("no such command")
+	ErrInvalidArgs   = errors.New("invalid arguments")
+	ErrInvalidConfig = errors.New("invalid config file")
 )
 
-func Core() int {
+func Core(version string) int {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
 	cmd := newCmd()
+	// --version is handled by urfave/cli, so it parses every flag itself
+	// and --generate-shell-completion reaches it untouched.
+	cmd.Version = version
 
 	err := cmd.Run(ctx, os.Args)
 	if err != nil {
@@ -51,70 +54,753 @@ func Core() int {
 	return ExitCodeOK
 }
 
+// sessionizer holds what every subcommand needs to find and read the config.
+type sessionizer struct {
+	filer *iohelper.Filer
+}
+
 func newCmd() *cli.Command {
+	s := &sessionizer{
+		filer: iohelper.NewFiler(),
+	}
+
 	return &cli.Command{
-		Name:   CommandName,
-		Usage:  CommandUsage,
-		Action: run,
+		Name:                  CommandName,
+		Usage:                 CommandUsage,
+		Description:           "Without a command, pick a running session or a project with fzf and attach to its session, creating it if needed.",
+		EnableShellCompletion: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:      "config",
+				Usage:     "config file, instead of $" + iohelper.EnvConfigFile + ", $XDG_CONFIG_HOME/tmux-sessionizer/config or " + iohelper.LegacyConfigFile,
+				TakesFile: true,
+			},
+			&cli.BoolFlag{
+				Name:  "refresh",
+				Usage: "walk every root again instead of taking unchanged ones from the project cache",
+			},
+			&cli.BoolFlag{
+				Name:  "as-window",
+				Usage: "open the picked entry as a window of the current session instead of a session of its own",
+			},
+		},
+		Action: s.newSession,
+		Commands: []*cli.Command{
+			{
+				Name:   "init",
+				Usage:  "create the config file with the default= prefix",
+				Action: s.init,
+			},
+			{
+				Name:      "register",
+				Usage:     "register a root whose subdirectories are projects, or a single project",
+				ArgsUsage: "<path/to/project>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "project",
+						Usage: "register the directory itself as a project",
+					},
+					&cli.BoolFlag{
+						Name:  "root",
+						Usage: "register the directory as a root whose subdirectories are projects",
+						Value: true,
+					},
+					&cli.IntFlag{
+						Name:  "depth",
+						Usage: "with --root, how many levels of subdirectories are projects",
+						Value: 1,
+					},
+				},
+				Action: s.register,
+			},
+			{
+				Name:  "import",
+				Usage: "pick directories known to zoxide, ghq or tmux with fzf and register them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "from",
+						Usage:    "where to import from, one of " + importSourcesUsage(),
+						Required: true,
+					},
+				},
+				Action: s.importProjects,
+			},
+			{
+				Name:   "list",
+				Usage:  "pick a running session with fzf and attach to it",
+				Action: s.list,
+			},
+			{
+				Name:   "delete",
+				Usage:  "pick running sessions with fzf and kill them",
+				Action: s.delete,
+			},
+			{
+				Name:          "rename",
+				Usage:         "rename a session and remember the name for its project",
+				ArgsUsage:     "[session-name|path/to/project] <new-name>",
+				Action:        s.rename,
+				ShellComplete: s.completeSessions,
+			},
+			{
+				Name:      "window",
+				Usage:     "open a project as a window of the current session, or select the window already in it",
+				ArgsUsage: "[path]",
+				Action:    s.window,
+			},
+			{
+				Name:      "popup",
+				Usage:     "run tmux-sessionizer, or one of its commands, in a tmux popup",
+				ArgsUsage: "[command [arguments...]]",
+				Flags: append(popupFlags(), &cli.StringFlag{
+					Name:  "client",
+					Usage: "tmux client to show the popup on, instead of the one tmux picks",
+				}),
+				Action: s.popup,
+			},
+			{
+				Name:  "keybindings",
+				Usage: "print tmux key bindings opening the picker in a popup, ready to be sourced",
+				Flags: append(popupFlags(),
+					&cli.StringFlag{
+						Name:  "key",
+						Value: "f",
+						Usage: "key, after the prefix, opening the picker",
+					},
+					&cli.StringFlag{
+						Name:  "window-key",
+						Value: "F",
+						Usage: "key, after the prefix, opening the picker that opens windows",
+					},
+				),
+				Action: s.keyBindings,
+			},
+			{
+				Name:      "scratch",
+				Usage:     "create a throwaway session in a temporary directory",
+				ArgsUsage: "[name]",
+				Action:    s.scratch,
+				Commands: []*cli.Command{
+					{
+						Name:   "prune",
+						Usage:  "kill detached scratch sessions and remove scratch directories left behind",
+						Action: s.pruneScratch,
+					},
+				},
+			},
+			{
+				Name:      "workspace",
+				Usage:     "open every project of a workspace",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "windows",
+						Usage: "open a single session with one window per project",
+					},
+				},
+				Action:        s.workspace,
+				ShellComplete: s.completeWorkspaces,
+			},
+			{
+				Name:      "clone",
+				Usage:     "clone a repository into <clone>/<host>/<owner>/<repository> and open it",
+				ArgsUsage: "<url>",
+				Action:    s.clone,
+			},
+			{
+				Name:          "worktree",
+				Usage:         "create a git worktree for a branch and open it",
+				ArgsUsage:     "<path/to/repository> <branch>",
+				Action:        s.worktree,
+				ShellComplete: s.completeProjects,
+				Commands: []*cli.Command{
+					{
+						Name:          "remove",
+						Usage:         "kill the session of a clean worktree and remove the worktree",
+						ArgsUsage:     "<path/to/repository> <branch>",
+						Action:        s.removeWorktree,
+						ShellComplete: s.completeProjects,
+					},
+				},
+			},
+			{
+				Name:   "doctor",
+				Usage:  "check tmux, fzf, the config file and the projects it lists",
+				Action: s.doctor,
+			},
+			{
+				Name:  "config",
+				Usage: "inspect the config file",
+				Commands: []*cli.Command{
+					{
+						Name:   "check",
+						Usage:  "report every problem of the config file with its line and column",
+						Action: s.checkConfig,
+					},
+				},
+			},
+			{
+				Name:  "cache",
+				Usage: "manage the cache of the projects found under each root",
+				Commands: []*cli.Command{
+					{
+						Name:   "clear",
+						Usage:  "remove the project cache, so every root is walked again",
+						Action: s.clearCache,
+					},
+				},
+			},
+			{
+				Name:   "daemon",
+				Usage:  "keep the projects in memory, watching the roots, so fzf opens with all of them at once",
+				Action: s.daemon,
+			},
+			newCompletionCmd(),
+		},
+	}
+}
+
+// popupFlags size and place the popup of the popup and keybindings commands.
+func popupFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "width",
+			Value: "80%",
+			Usage: "popup width, in cells or a percentage of the terminal",
+		},
+		&cli.StringFlag{
+			Name:  "height",
+			Value: "80%",
+			Usage: "popup height, in cells or a percentage of the terminal",
+		},
+		&cli.StringFlag{
+			Name:  "x",
+			Value: "C",
+			Usage: "popup column, or a position tmux knows such as C for centered",
+		},
+		&cli.StringFlag{
+			Name:  "y",
+			Value: "C",
+			Usage: "popup row, or a position tmux knows such as C for centered",
+		},
+	}
+}
+
+func popupOf(cmd *cli.Command) tmux.Popup {
+	return tmux.Popup{
+		Client: cmd.String("client"),
+		Width:  cmd.String("width"),
+		Height: cmd.String("height"),
+		X:      cmd.String("x"),
+		Y:      cmd.String("y"),
+	}
+}
+
+// args returns the arguments of cmd, or an error when their number is not
+// within [minArgs, maxArgs].
+func args(cmd *cli.Command, minArgs int, maxArgs int) ([]string, error) {
+	a := cmd.Args().Slice()
+	if len(a) < minArgs || len(a) > maxArgs {
+		return nil, fmt.Errorf("usage: %s %s %s:%w", CommandName, cmd.Name, cmd.ArgsUsage, ErrInvalidArgs)
+	}
+	return a, nil
+}
+
+func (s *sessionizer) configFile(cmd *cli.Command) (iohelper.ConfigFile, error) {
+	return iohelper.ResolveConfigFile(s.filer, cmd.String("config"), os.Getenv)
+}
+
+func (s *sessionizer) configFileAbs(cmd *cli.Command) (string, error) {
+	configFile, err := s.configFile(cmd)
+	return configFile.Path, err
+}
+
+// setup reads the config file and gathers the running sessions, which all
+// commands but init and register need.
+func (s *sessionizer) setup(ctx context.Context, cmd *cli.Command) (*iohelper.Config, *handler.ProjectHandler, handler.ISessionHandler, error) {
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	config, err := s.readConfig(ctx, cmd, configFileAbs)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read config:%w", err)
+	}
+
+	return config, handler.NewProjectHandler(configFileAbs), buildSessionHandler(ctx, config), nil
 }
 
-func run(ctx context.Context, cmd *cli.Command) error {
-	filer := iohelper.NewFiler()
-	configFile, err := filer.ExpandTildeAsHomeDir(configFile)
+// newSession opens fzf right away and streams the projects into it while the
+// roots are still being walked. After killing or renaming a session, the
+// picker is shown again.
+func (s *sessionizer) newSession(ctx context.Context, cmd *cli.Command) error {
+	configFileAbs, err := s.configFileAbs(cmd)
 	if err != nil {
 		return err
 	}
-	configFileAbs, err := filepath.Abs(configFile)
+	// The config is validated before the arguments, so a broken config
+	// is reported whatever was typed.
+	if err := validate.ValidateConfig(configFileAbs); err != nil {
+		return fmt.Errorf("failed to validate config file:%w", err)
+	}
+	if cmd.Args().Present() {
+		return ErrNoSuchCmd
+	}
+
+	return s.pickLoop(ctx, cmd, configFileAbs, cmd.Bool("as-window"))
+}
+
+// pickLoop shows the picker until something else than killing or renaming a
+// session was done. With asWindow, enter opens a window rather than a session.
+func (s *sessionizer) pickLoop(ctx context.Context, cmd *cli.Command, configFileAbs string, asWindow bool) error {
+	for {
+		action, err := s.pick(ctx, cmd, configFileAbs, asWindow)
+		if err != nil || !action.Reopens() {
+			return err
+		}
+	}
+}
+
+// pick shows the picker once and does what was picked.
+func (s *sessionizer) pick(ctx context.Context, cmd *cli.Command, configFileAbs string, asWindow bool) (handler.PickerAction, error) {
+	tmux := tmux.NewTmux()
+	sessions := gatherSessions(ctx, tmux)
+	picker, err := handler.StartProjectPicker(ctx, sessions)
+	if err != nil {
+		return "", err
+	}
+	// Discovery stops once fzf exits, so an entry picked early is acted on
+	// without waiting for the roots still being walked.
+	discoverCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	go func() {
+		select {
+		case <-picker.Exited():
+			stop(iohelper.ErrDiscoveryStopped)
+		case <-discoverCtx.Done():
+		}
+	}()
+	config, err := s.configParser(ctx, cmd, configFileAbs).OnRoots(picker.AddSessions).OnProject(picker.Add).ReadConfig(discoverCtx, s.filer, configFileAbs)
+	if err != nil {
+		picker.Abort()
+		return "", fmt.Errorf("failed to read config:%w", err)
+	}
+	action, picked, err := picker.Pick()
 	if err != nil {
+		return "", err
+	}
+	// Written while fzf is open, the warning would be drawn over.
+	warnSkipped(cmd, config)
+	if asWindow && action == handler.ActionOpen {
+		action = handler.ActionWindow
+	}
+
+	sh := newSessionHandler(config, tmux, sessions)
+	if action != handler.ActionRename {
+		return action, sh.Act(ctx, action, picked)
+	}
+	name, err := sh.AskSessionName(ctx, picked)
+	if err != nil || len(name) == 0 {
+		return action, err
+	}
+	return action, renameSession(ctx, sh, handler.NewProjectHandler(configFileAbs), picked, name)
+}
+
+func (s *sessionizer) doctor(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
 		return err
 	}
+	configFile, err := s.configFile(cmd)
+	if err != nil {
+		return err
+	}
+	// doctor must work exactly when the config does not, so it reads it itself.
+	dh := handler.NewDoctorHandler(configFile, s.filer, tmux.NewTmux(), newSessionNameTransformer())
+	return handler.Report(cmd.Root().Writer, dh.Diagnose(ctx))
+}
 
-	return runWithHandler(ctx, cmd, filer, configFileAbs)
+func (s *sessionizer) checkConfig(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return err
+	}
+	diagnostics, err := validate.CheckConfig(s.filer, configFileAbs)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.Root().Writer
+	for _, d := range diagnostics {
+		fmt.Fprintln(w, d)
+	}
+	if validate.HasErrors(diagnostics) {
+		return fmt.Errorf("%s:%w", configFileAbs, ErrInvalidConfig)
+	}
+	if len(diagnostics) == 0 {
+		fmt.Fprintf(w, "%s: no problems found\n", configFileAbs)
+	}
+	return nil
 }
 
-func runWithHandler(
-	ctx context.Context,
-	cmd *cli.Command,
-	filer *iohelper.Filer,
-	configFileAbs string,
-) error {
-	args := cmd.Args().Slice()
+func (s *sessionizer) clearCache(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	cacheFile, err := iohelper.CacheFile(s.filer, os.Getenv)
+	if err != nil {
+		return err
+	}
+	return iohelper.ClearProjectCache(cacheFile)
+}
+
+// daemon serves the projects of the config file until interrupted. Every
+// other command falls back to walking the roots when it is not running.
+func (s *sessionizer) daemon(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return err
+	}
+	if err := validate.ValidateConfig(configFileAbs); err != nil {
+		return fmt.Errorf("failed to validate config file:%w", err)
+	}
+	// Interrupt is handled by Core already; a daemon is stopped with SIGTERM as often.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM)
+	defer stop()
+
+	socketPath := daemon.SocketPath(os.Getenv)
+	l, err := daemon.Listen(socketPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Root().Writer, "serving %s on %s\n", configFileAbs, socketPath)
+
+	// --refresh only applies to the first walk; the cache keeps later
+	// reloads down to the roots that changed.
+	refresh := cmd.Bool("refresh")
+	load := func(ctx context.Context) (*iohelper.Config, error) {
+		config, err := s.cachedConfigParser(refresh).ReadConfig(ctx, s.filer, configFileAbs)
+		refresh = false
+		return config, err
+	}
+	return daemon.New(configFileAbs, load, cmd.Root().ErrWriter).Run(ctx, l)
+}
+
+func (s *sessionizer) init(ctx context.Context, cmd *cli.Command) error {
 	// initialization does not need config file validation
-	ph := handler.NewProjectHandler(configFileAbs)
-	if len(args) == 1 && args[0] == "init" {
-		return ph.Init(ctx, configFileAbs)
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return err
+	}
+	return handler.NewProjectHandler(configFileAbs).Init(ctx, configFileAbs)
+}
+
+func (s *sessionizer) register(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 1, 1)
+	if err != nil {
+		return err
 	}
-	config, err := readConfig(ctx, filer, configFileAbs)
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return err
+	}
+	config, err := s.readConfig(ctx, cmd, configFileAbs)
 	if err != nil {
 		return fmt.Errorf("failed to read config:%w", err)
 	}
+	depth, err := registerDepth(cmd)
+	if err != nil {
+		return err
+	}
 	// register does not require to gather tmux sessions
-	if len(args) == 2 && args[0] == "register" {
-		return registerProject(ctx, ph, filer, config, args[1])
+	return registerProject(ctx, handler.NewProjectHandler(configFileAbs), s.filer, config, a[0], depth)
+}
+
+// registerDepth reads the flags of register: 0 for --project, else the depth
+// of the root.
+func registerDepth(cmd *cli.Command) (int, error) {
+	if cmd.Bool("project") {
+		if cmd.IsSet("root") || cmd.IsSet("depth") {
+			return 0, fmt.Errorf("--project cannot be combined with --root or --depth:%w", ErrInvalidArgs)
+		}
+		return 0, nil
 	}
 
-	sh := buildSessionHandler(ctx, config)
-	if len(args) == 1 && args[0] == "list" {
-		return sh.GrabExistingSession(ctx)
-	} else if len(args) == 1 && args[0] == "delete" {
-		return sh.DeleteSessions(ctx)
-	} else if len(args) > 0 {
-		return ErrNoSuchCmd
-	} else {
-		return sh.NewSession(ctx)
+	if !cmd.Bool("root") {
+		return 0, fmt.Errorf("either --root or --project is needed:%w", ErrInvalidArgs)
+	}
+	depth := int(cmd.Int("depth"))
+	if depth < 1 {
+		return 0, fmt.Errorf("--depth must be at least 1:%w", ErrInvalidArgs)
 	}
+	return depth, nil
+}
+
+// importProjects registers directories picked among those of a zoxide
+// database, the ghq roots or running tmux sessions. Roots come from ghq, the
+// others give projects.
+func (s *sessionizer) importProjects(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	source, err := handler.ParseImportSource(cmd.String("from"))
+	if err != nil {
+		return fmt.Errorf("--from must be one of %s, %w:%w", importSourcesUsage(), err, ErrInvalidArgs)
+	}
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return err
+	}
+	config, err := s.readConfig(ctx, cmd, configFileAbs)
+	if err != nil {
+		return fmt.Errorf("failed to read config:%w", err)
+	}
+
+	ih := handler.NewImportHandler(config, handler.NewProjectHandler(configFileAbs), tmux.NewTmux())
+	imported, err := ih.Import(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.Root().Writer
+	if len(imported) == 0 {
+		fmt.Fprintf(w, "nothing to import from %s\n", source)
+	}
+	for _, dir := range imported {
+		fmt.Fprintf(w, "registered %s\n", dir)
+	}
+	return nil
+}
+
+func importSourcesUsage() string {
+	sources := make([]string, 0, len(handler.ImportSources))
+	for _, source := range handler.ImportSources {
+		sources = append(sources, string(source))
+	}
+	return strings.Join(sources, "|")
+}
+
+func (s *sessionizer) list(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	_, _, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return sh.GrabExistingSession(ctx)
+}
+
+func (s *sessionizer) delete(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	_, _, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return sh.DeleteSessions(ctx)
+}
+
+func (s *sessionizer) rename(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 1, 2)
+	if err != nil {
+		return err
+	}
+	_, ph, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if len(a) == 1 {
+		return renameSession(ctx, sh, ph, "", a[0])
+	}
+	return renameSession(ctx, sh, ph, a[0], a[1])
+}
+
+func (s *sessionizer) window(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(a) == 0 {
+		configFileAbs, err := s.configFileAbs(cmd)
+		if err != nil {
+			return err
+		}
+		if err := validate.ValidateConfig(configFileAbs); err != nil {
+			return fmt.Errorf("failed to validate config file:%w", err)
+		}
+		return s.pickLoop(ctx, cmd, configFileAbs, true)
+	}
+
+	path, err := s.filer.ExpandTildeAsHomeDir(a[0])
+	if err != nil {
+		return err
+	}
+	pathAbs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s to absolute path:%w", path, err)
+	}
+	if info, err := os.Stat(pathAbs); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory:%w", pathAbs, ErrInvalidArgs)
+	}
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return err
+	}
+	config, err := s.readSettings(ctx, configFileAbs)
+	if err != nil {
+		return fmt.Errorf("failed to read config:%w", err)
+	}
+	warnSkipped(cmd, config)
+	return buildSessionHandler(ctx, config).OpenWindow(ctx, pathAbs)
+}
+
+func (s *sessionizer) popup(ctx context.Context, cmd *cli.Command) error {
+	ph, err := s.popupHandler(cmd)
+	if err != nil {
+		return err
+	}
+	return ph.Open(ctx, popupOf(cmd), cmd.Args().Slice())
+}
+
+func (s *sessionizer) keyBindings(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	ph, err := s.popupHandler(cmd)
+	if err != nil {
+		return err
+	}
+	return ph.WriteKeyBindings(cmd.Root().Writer, popupOf(cmd), []handler.KeyBinding{
+		{Key: cmd.String("key")},
+		{Key: cmd.String("window-key"), Args: []string{"window"}},
+	})
+}
+
+// popupHandler runs this very executable in the popup, with the config file
+// resolved here and the flags given here.
+func (s *sessionizer) popupHandler(cmd *cli.Command) (*handler.PopupHandler, error) {
+	configFileAbs, err := s.configFileAbs(cmd)
+	if err != nil {
+		return nil, err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tmux-sessionizer executable:%w", err)
+	}
+
+	self := []string{executable, "--config", configFileAbs}
+	for _, flag := range []string{"refresh", "as-window"} {
+		if cmd.Bool(flag) {
+			self = append(self, "--"+flag)
+		}
+	}
+	return handler.NewPopupHandler(tmux.NewTmux(), self), nil
+}
+
+func (s *sessionizer) scratch(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 1); err != nil {
+		return err
+	}
+	_, _, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return sh.NewScratchSession(ctx, cmd.Args().First())
+}
+
+func (s *sessionizer) pruneScratch(ctx context.Context, cmd *cli.Command) error {
+	if _, err := args(cmd, 0, 0); err != nil {
+		return err
+	}
+	_, _, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	removed, err := sh.Prune(ctx)
+	for _, dir := range removed {
+		fmt.Fprintf(cmd.Root().Writer, "removed %s\n", dir)
+	}
+	return err
+}
+
+func (s *sessionizer) workspace(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 1, 1)
+	if err != nil {
+		return err
+	}
+	_, _, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return sh.OpenWorkspace(ctx, a[0], cmd.Bool("windows"))
+}
+
+func (s *sessionizer) clone(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 1, 1)
+	if err != nil {
+		return err
+	}
+	config, ph, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return clone(ctx, handler.NewCloneHandler(config, ph, sh), s.filer, a[0])
+}
+
+func (s *sessionizer) worktree(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 2, 2)
+	if err != nil {
+		return err
+	}
+	config, ph, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return worktree(ctx, handler.NewWorktreeHandler(config, ph, sh), s.filer, a[0], a[1], false)
+}
+
+func (s *sessionizer) removeWorktree(ctx context.Context, cmd *cli.Command) error {
+	a, err := args(cmd, 2, 2)
+	if err != nil {
+		return err
+	}
+	config, ph, sh, err := s.setup(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return worktree(ctx, handler.NewWorktreeHandler(config, ph, sh), s.filer, a[0], a[1], true)
 }
 
 func buildSessionHandler(ctx context.Context, config *iohelper.Config) handler.ISessionHandler {
 	tmux := tmux.NewTmux()
+	return newSessionHandler(config, tmux, gatherSessions(ctx, tmux))
+}
+
+func newSessionHandler(config *iohelper.Config, tmux *tmux.Tmux, sessions map[types.String]*session.Session) handler.ISessionHandler {
+	sm := session.NewSessionManager(sessions, newSessionNameTransformer()).WithAliases(config.Aliases)
+	return handler.NewSessionHandler(config, sm, tmux)
+}
+
+func gatherSessions(ctx context.Context, tmux *tmux.Tmux) map[types.String]*session.Session {
 	sessions, err := tmux.GatherExistingSessions(ctx)
 	if err != nil {
 		// tmux may simply not be running yet; start from an empty session map.
-		sessions = make(map[types.String]*session.Session, 0)
+		return make(map[types.String]*session.Session, 0)
 	}
+	return sessions
+}
 
-	sessionNameTransformer := session.NewTransformer().WithRule(
+// newSessionNameTransformer makes project paths acceptable as tmux session
+// names, which must not contain '.' or ':'.
+func newSessionNameTransformer() *session.Transformer {
+	return session.NewTransformer().WithRule(
 		session.NewTransformRule(
 			func(in string) string { return strings.ReplaceAll(in, ".", "_") },
 			func(in string) string { return strings.ReplaceAll(in, "_", ".") },
@@ -124,29 +810,80 @@ func buildSessionHandler(ctx context.Context, config *iohelper.Config) handler.I
 			func(in string) string { return strings.ReplaceAll(in, ";", ":") },
 		),
 	)
-	sm := session.NewSessionManager(sessions, sessionNameTransformer)
-	return handler.NewSessionHandler(config, sm, tmux)
 }
 
-func readConfig(_ context.Context, filer *iohelper.Filer, configFileAbs string) (*iohelper.Config, error) {
+// readConfig validates and reads the config file, taking the projects from
+// the daemon or, for unchanged roots, from the project cache.
+func (s *sessionizer) readConfig(ctx context.Context, cmd *cli.Command, configFileAbs string) (*iohelper.Config, error) {
 	if err := validate.ValidateConfig(configFileAbs); err != nil {
 		return nil, fmt.Errorf("failed to validate config file:%w", err)
 	}
 
-	configParser := iohelper.NewConfigParser()
-	config, err := configParser.ReadConfig(filer, configFileAbs)
+	config, err := s.configParser(ctx, cmd, configFileAbs).ReadConfig(ctx, s.filer, configFileAbs)
 	if err != nil {
 		return nil, err
 	}
+	warnSkipped(cmd, config)
 	return config, nil
 }
 
+// warnSkipped tells about the lines left out of the config and the roots
+// skipped for taking too long, so missing projects do not go unnoticed, e.g.
+// after a typo in $WORK/repos.
+func warnSkipped(cmd *cli.Command, config *iohelper.Config) {
+	for _, err := range config.Skipped {
+		fmt.Fprintf(cmd.Root().ErrWriter, "warning: %v, it is skipped\n", err)
+	}
+	for _, slow := range config.SlowRoots {
+		fmt.Fprintf(cmd.Root().ErrWriter, "warning: %s took longer than %s to read, its projects are skipped\n", slow.Value(), config.DiscoveryTimeout)
+	}
+}
+
+// readSettings reads configFileAbs for a command given its directory, which
+// needs the settings, such as env=, but none of the projects: the roots are
+// not walked, only the projects of a running daemon are taken.
+func (s *sessionizer) readSettings(ctx context.Context, configFileAbs string) (*iohelper.Config, error) {
+	if err := validate.ValidateConfig(configFileAbs); err != nil {
+		return nil, fmt.Errorf("failed to validate config file:%w", err)
+	}
+	index, err := daemon.Query(ctx, daemon.SocketPath(os.Getenv), configFileAbs)
+	if err != nil {
+		// NOTE: the worktrees are then unknown, so a worktree starts without the env of its repository.
+		index = &iohelper.ProjectIndex{}
+	}
+	return iohelper.NewConfigParser().WithIndex(index).ReadConfig(ctx, s.filer, configFileAbs)
+}
+
+// configParser takes the projects from the daemon when one serves
+// configFileAbs, and walks the roots with the project cache otherwise.
+// --refresh always walks them.
+func (s *sessionizer) configParser(ctx context.Context, cmd *cli.Command, configFileAbs string) *iohelper.ConfigParser {
+	if !cmd.Bool("refresh") {
+		if index, err := daemon.Query(ctx, daemon.SocketPath(os.Getenv), configFileAbs); err == nil {
+			return iohelper.NewConfigParser().WithIndex(index)
+		}
+	}
+	return s.cachedConfigParser(cmd.Bool("refresh"))
+}
+
+func (s *sessionizer) cachedConfigParser(refresh bool) *iohelper.ConfigParser {
+	cacheFile, err := iohelper.CacheFile(s.filer, os.Getenv)
+	if err != nil {
+		// NOTE: without a home directory there is nowhere to cache, the roots are walked every time.
+		return iohelper.NewConfigParser()
+	}
+	return iohelper.NewConfigParser().WithCache(iohelper.LoadProjectCache(cacheFile, refresh))
+}
+
+// registerProject registers rawPath as a root with depth, or as a project
+// itself when depth is 0.
 func registerProject(
 	ctx context.Context,
 	ph *handler.ProjectHandler,
 	filer *iohelper.Filer,
 	config *iohelper.Config,
 	rawPath string,
+	depth int,
 ) error {
 	registerPath, err := filer.ExpandTildeAsHomeDir(rawPath)
 	if err != nil {
@@ -165,11 +902,83 @@ func registerProject(
 		return fmt.Errorf("project path %s must not contain ',', the config file separator", registerAbs)
 	}
 
+	// A path is either a root or a project; registering it as both would
+	// list it twice, so both kinds are checked whatever is registered now.
 	register := types.NewString(registerAbs)
-	for _, project := range config.Registered {
-		if register.Value() == project.Value() {
-			return errors.New("tmux-sessionizer does not allow duplicated project registration")
+	for _, registered := range []struct {
+		kind  string
+		paths []types.String
+	}{
+		{kind: "root", paths: config.Registered},
+		{kind: "project", paths: config.RegisteredProjects},
+	} {
+		for _, r := range registered.paths {
+			if register.Value() == r.Value() {
+				return fmt.Errorf("tmux-sessionizer does not allow duplicated project registration, %s is already registered as a %s", registerAbs, registered.kind)
+			}
 		}
 	}
-	return ph.Register(ctx, registerAbs)
+
+	if depth == 0 {
+		return ph.RegisterProject(ctx, registerAbs)
+	}
+	return ph.RegisterRoot(ctx, registerAbs, depth)
+}
+
+func renameSession(
+	ctx context.Context,
+	sh handler.ISessionHandler,
+	ph *handler.ProjectHandler,
+	target string,
+	rawName string,
+) error {
+	renamed, err := sh.RenameSession(ctx, target, rawName)
+	if err != nil {
+		return fmt.Errorf("failed to rename session:%w", err)
+	}
+	if renamed.Foreign {
+		// It belongs to no project whose sessions the alias would name.
+		return nil
+	}
+	// Without the alias, the next NewSession for this project would come
+	// back under the old, path derived name once the session is killed.
+	key := renamed.Key()
+	return ph.Alias(ctx, key.Value(), renamed.Name.Value())
+}
+
+func worktree(
+	ctx context.Context,
+	wh *handler.WorktreeHandler,
+	filer *iohelper.Filer,
+	rawRepo string,
+	branch string,
+	remove bool,
+) error {
+	repo, err := filer.ExpandTildeAsHomeDir(rawRepo)
+	if err != nil {
+		return err
+	}
+	repoAbs, err := filepath.Abs(repo)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s to absolute path:%w", repo, err)
+	}
+
+	if remove {
+		return wh.Remove(ctx, repoAbs, branch)
+	}
+	return wh.Create(ctx, repoAbs, branch)
+}
+
+func clone(ctx context.Context, ch *handler.CloneHandler, filer *iohelper.Filer, remote string) error {
+	// A local repository may be given relative to the CWD, but it is cloned
+	// from elsewhere and must be named by where it lives.
+	if local, err := filer.ExpandTildeAsHomeDir(remote); err == nil && filer.Exists(local) == nil {
+		localAbs, err := filepath.Abs(local)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s to absolute path:%w", local, err)
+		}
+		remote = localAbs
+	}
+
+	return ch.Clone(ctx, remote)
 }
@@ -1,15 +1,17 @@
 package cmd
 
 import (
-	"context"
+	"bytes"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
-	"github.com/urfave/cli/v3"
 )
 
 // urfave/cli/v3 mutates package-level state (HelpFlag etc.) inside
@@ -19,23 +21,35 @@ import (
 //nolint:gochecknoglobals // must be shared across parallel tests to serialize Run.
 var cmdRunMu sync.Mutex
 
-// runTestCmd drives runWithHandler through a real cli.Command with a
-// temporary config file, so tests exercise the actual dispatch logic
-// without touching the user's config.
-func runTestCmd(t *testing.T, configFileAbs string, args ...string) error {
+// TestMain keeps the project cache of the commands under test away from the
+// user's cache directory.
+func TestMain(m *testing.M) {
+	cacheHome, err := os.MkdirTemp("", "tmux-sessionizer-cache-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", cacheHome)
+	// A daemon the user runs must not answer for the test configs.
+	os.Setenv("XDG_RUNTIME_DIR", cacheHome)
+	code := m.Run()
+	os.RemoveAll(cacheHome)
+	os.Exit(code)
+}
+
+// runTestCmd runs the real command tree against a temporary config file,
+// so tests exercise the actual dispatch logic without touching the user's
+// config. It returns what the command wrote to its Writer.
+func runTestCmd(t *testing.T, configFileAbs string, args ...string) (string, error) {
 	t.Helper()
 
-	cmd := &cli.Command{
-		Name:  "mock tmux-sessionizer",
-		Usage: "mock tmux session manager",
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			return runWithHandler(ctx, cmd, iohelper.NewFiler(), configFileAbs)
-		},
-	}
+	var out bytes.Buffer
+	cmd := newCmd()
+	cmd.Writer = &out
 
 	cmdRunMu.Lock()
 	defer cmdRunMu.Unlock()
-	return cmd.Run(t.Context(), append([]string{"tmux-sessionizer"}, args...))
+	err := cmd.Run(t.Context(), append([]string{CommandName, "--config", configFileAbs}, args...))
+	return out.String(), err
 }
 
 func writeConfigFile(t *testing.T, content string) string {
@@ -58,24 +72,24 @@ func readConfigFile(t *testing.T, configFileAbs string) string {
 	return string(b)
 }
 
-func TestRunWithHandler_InvalidCommand_ReturnsErrNoSuchCmd(t *testing.T) {
+func TestCmd_InvalidCommand_ReturnsErrNoSuchCmd(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
 
-	err := runTestCmd(t, configFileAbs, "invalid")
+	_, err := runTestCmd(t, configFileAbs, "invalid")
 
 	if !errors.Is(err, ErrNoSuchCmd) {
 		t.Errorf("expected ErrNoSuchCmd, got %v", err)
 	}
 }
 
-func TestRunWithHandler_Init_CreatesConfigFileWithPrefix(t *testing.T) {
+func TestCmd_Init_CreatesConfigFileWithPrefix(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
 
-	if err := runTestCmd(t, configFileAbs, "init"); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "init"); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -84,13 +98,13 @@ func TestRunWithHandler_Init_CreatesConfigFileWithPrefix(t *testing.T) {
 	}
 }
 
-func TestRunWithHandler_Init_KeepsExistingConfigIntact(t *testing.T) {
+func TestCmd_Init_KeepsExistingConfigIntact(t *testing.T) {
 	t.Parallel()
 
 	content := iohelper.ConfigPrefix + "/home/user/project"
 	configFileAbs := writeConfigFile(t, content)
 
-	if err := runTestCmd(t, configFileAbs, "init"); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "init"); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -99,13 +113,13 @@ func TestRunWithHandler_Init_KeepsExistingConfigIntact(t *testing.T) {
 	}
 }
 
-func TestRunWithHandler_Register_AppendsFirstProjectWithoutComma(t *testing.T) {
+func TestCmd_Register_AppendsFirstProjectWithoutComma(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
 	project := t.TempDir()
 
-	if err := runTestCmd(t, configFileAbs, "register", project); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", project); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -115,16 +129,16 @@ func TestRunWithHandler_Register_AppendsFirstProjectWithoutComma(t *testing.T) {
 	}
 }
 
-func TestRunWithHandler_Register_SeparatesSecondProjectWithComma(t *testing.T) {
+func TestCmd_Register_SeparatesSecondProjectWithComma(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
 	first, second := t.TempDir(), t.TempDir()
 
-	if err := runTestCmd(t, configFileAbs, "register", first); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", first); err != nil {
 		t.Fatalf("expected no error on first register, got %v", err)
 	}
-	if err := runTestCmd(t, configFileAbs, "register", second); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", second); err != nil {
 		t.Fatalf("expected no error on second register, got %v", err)
 	}
 
@@ -135,7 +149,7 @@ func TestRunWithHandler_Register_SeparatesSecondProjectWithComma(t *testing.T) {
 }
 
 //nolint:paralleltest // t.Chdir is incompatible with t.Parallel.
-func TestRunWithHandler_Register_ResolvesRelativePathToAbsolute(t *testing.T) {
+func TestCmd_Register_ResolvesRelativePathToAbsolute(t *testing.T) {
 	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
 	parent := t.TempDir()
 	if err := os.Mkdir(filepath.Join(parent, "project"), 0o755); err != nil {
@@ -143,7 +157,7 @@ func TestRunWithHandler_Register_ResolvesRelativePathToAbsolute(t *testing.T) {
 	}
 	t.Chdir(parent)
 
-	if err := runTestCmd(t, configFileAbs, "register", "project"); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", "project"); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -159,7 +173,7 @@ func TestRunWithHandler_Register_ResolvesRelativePathToAbsolute(t *testing.T) {
 	}
 }
 
-func TestRunWithHandler_Register_RejectsPathWithComma(t *testing.T) {
+func TestCmd_Register_RejectsPathWithComma(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
@@ -168,7 +182,7 @@ func TestRunWithHandler_Register_RejectsPathWithComma(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := runTestCmd(t, configFileAbs, "register", project); err == nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", project); err == nil {
 		t.Error("expected error for path containing a comma, got nil")
 	}
 
@@ -177,16 +191,16 @@ func TestRunWithHandler_Register_RejectsPathWithComma(t *testing.T) {
 	}
 }
 
-func TestRunWithHandler_Register_RejectsDuplicatedProject(t *testing.T) {
+func TestCmd_Register_RejectsDuplicatedProject(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
 	project := t.TempDir()
 
-	if err := runTestCmd(t, configFileAbs, "register", project); err != nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", project); err != nil {
 		t.Fatalf("expected no error on first register, got %v", err)
 	}
-	if err := runTestCmd(t, configFileAbs, "register", project); err == nil {
+	if _, err := runTestCmd(t, configFileAbs, "register", project); err == nil {
 		t.Error("expected error on duplicated register, got nil")
 	}
 
@@ -196,14 +210,144 @@ func TestRunWithHandler_Register_RejectsDuplicatedProject(t *testing.T) {
 	}
 }
 
-func TestRunWithHandler_UninitializedConfig_FailsValidation(t *testing.T) {
+func TestCmd_Register_RejectsProjectAlreadyRegisteredAsRoot(t *testing.T) {
+	t.Parallel()
+
+	project := t.TempDir()
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix+project)
+
+	if _, err := runTestCmd(t, configFileAbs, "register", "--project", project); err == nil {
+		t.Error("expected error on duplicated register, got nil")
+	}
+	if _, err := runTestCmd(t, configFileAbs, "register", "--project", t.TempDir()); err != nil {
+		t.Fatalf("expected no error on project register, got %v", err)
+	}
+}
+
+func TestCmd_Register_WarnsAboutUndefinedVariable(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix+"$TMUX_SESSIONIZER_SURELY_UNDEFINED/src")
+
+	var stderr bytes.Buffer
+	cmd := newCmd()
+	cmd.Writer, cmd.ErrWriter = io.Discard, &stderr
+	cmdRunMu.Lock()
+	err := cmd.Run(t.Context(), []string{CommandName, "--config", configFileAbs, "register", "--project", t.TempDir()})
+	cmdRunMu.Unlock()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.Contains(stderr.String(), "warning: "+configFileAbs+":1:") || !strings.Contains(stderr.String(), "undefined environment variable") {
+		t.Errorf("expected a warning naming the line, got %q", stderr.String())
+	}
+}
+
+func TestCmd_Register_RootWithDepth(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+	root := t.TempDir()
+
+	if _, err := runTestCmd(t, configFileAbs, "register", "--root", "--depth", "3", root); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := iohelper.ConfigPrefix + "\n" + iohelper.RootPrefix + "3," + root
+	if got := readConfigFile(t, configFileAbs); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCmd_Register_RejectsInvalidFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "project and root", args: []string{"--project", "--root"}},
+		{name: "project and depth", args: []string{"--project", "--depth", "2"}},
+		{name: "neither root nor project", args: []string{"--root=false"}},
+		{name: "zero depth", args: []string{"--depth", "0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+			_, err := runTestCmd(t, configFileAbs, append(append([]string{"register"}, tt.args...), t.TempDir())...)
+
+			if !errors.Is(err, ErrInvalidArgs) {
+				t.Errorf("expected ErrInvalidArgs, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCmd_Import_RejectsUnknownSource(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+
+	_, err := runTestCmd(t, configFileAbs, "import", "--from", "autojump")
+
+	if !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+}
+
+func TestCmd_Window_RejectsMissingDirectory(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+
+	_, err := runTestCmd(t, configFileAbs, "window", filepath.Join(t.TempDir(), "missing"))
+
+	if !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+}
+
+//nolint:paralleltest // other commands would fill the shared cache in between.
+func TestCmd_CacheClear_RemovesProjectCache(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	project := t.TempDir()
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix+filepath.Dir(project))
+	// A root changed just now is not cached.
+	then := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Dir(project), then, then); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(cacheHome, "tmux-sessionizer", "projects.json")
+
+	// Completing reads the config, which fills the cache.
+	if _, err := runTestCmd(t, configFileAbs, "worktree", "--generate-shell-completion"); err != nil {
+		t.Fatalf("expected no error on completion, got %v", err)
+	}
+	if _, err := os.Stat(cacheFile); err != nil {
+		t.Fatalf("expected project cache at %s, got %v", cacheFile, err)
+	}
+
+	if _, err := runTestCmd(t, configFileAbs, "cache", "clear"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Errorf("expected project cache to be removed, got %v", err)
+	}
+}
+
+func TestCmd_UninitializedConfig_FailsValidation(t *testing.T) {
 	t.Parallel()
 
 	configFileAbs := writeConfigFile(t, "not a tmux-sessionizer config\n")
 
 	// "invalid" never dispatches to fzf/tmux, so reaching ErrNoSuchCmd
 	// would mean the broken config slipped through validation.
-	err := runTestCmd(t, configFileAbs, "invalid")
+	_, err := runTestCmd(t, configFileAbs, "invalid")
 
 	if err == nil {
 		t.Fatal("expected validation error, got nil")
@@ -212,3 +356,44 @@ func TestRunWithHandler_UninitializedConfig_FailsValidation(t *testing.T) {
 		t.Errorf("expected validation error before dispatch, got ErrNoSuchCmd")
 	}
 }
+
+func TestCmd_Register_RequiresPath(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix)
+
+	_, err := runTestCmd(t, configFileAbs, "register")
+
+	if !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected ErrInvalidArgs, got %v", err)
+	}
+}
+
+func TestCmd_ConfigCheck_FailsOnErrors(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfigFile(t, iohelper.ConfigPrefix+"\nbogus=1\n")
+
+	out, err := runTestCmd(t, configFileAbs, "config", "check")
+
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+	if want := configFileAbs + `:2:1: error: unknown key "bogus"` + "\n"; out != want {
+		t.Errorf("expected output %q, got %q", want, out)
+	}
+}
+
+func TestCmd_Init_CreatesConfigDirectory(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := filepath.Join(t.TempDir(), "tmux-sessionizer", "config")
+
+	if _, err := runTestCmd(t, configFileAbs, "init"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := readConfigFile(t, configFileAbs); got != iohelper.ConfigPrefix {
+		t.Errorf("expected config %q, got %q", iohelper.ConfigPrefix, got)
+	}
+}
@@ -3,13 +3,15 @@ module github.com/TlexCypher/my-tmux-sessionizer
 go 1.25.0
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/google/go-cmp v0.7.0
 	github.com/google/uuid v1.6.0
 	github.com/samber/lo v1.50.0
 	github.com/urfave/cli/v3 v3.0.0-beta1
+	golang.org/x/sync v0.22.0
 )
 
 require (
-	golang.org/x/sync v0.22.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 )
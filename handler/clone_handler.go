@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+)
+
+type CloneHandler struct {
+	config   *iohelper.Config
+	git      *git.Git
+	projects *ProjectHandler
+	sessions ISessionHandler
+}
+
+func NewCloneHandler(config *iohelper.Config, projects *ProjectHandler, sessions ISessionHandler) *CloneHandler {
+	return &CloneHandler{
+		config:   config,
+		git:      git.NewGit(),
+		projects: projects,
+		sessions: sessions,
+	}
+}
+
+// Clone clones remote into <clone root>/<host>/<owner>/<repository>, registers
+// the clone root like `import --from ghq` does so the repository shows up as a
+// project, and opens a session on it. A repository cloned before is opened
+// without cloning again.
+func (ch *CloneHandler) Clone(ctx context.Context, remote string) error {
+	dir, err := git.RemoteDir(remote)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(ch.config.CloneRoot.Value(), dir)
+
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(dest), dirPermission); err != nil {
+			return fmt.Errorf("failed to create clone directory:%w", err)
+		}
+		if err := ch.git.Clone(ctx, remote, dest); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to get status of %s:%w", dest, err)
+	}
+
+	// A root the clone is found under already, e.g. a ghq root imported
+	// before, would only be nested by another one.
+	if _, found := ch.config.RootOf(dest); !found {
+		if err := ch.projects.RegisterRootOnce(ctx, ch.config, ch.config.CloneRoot.Value(), ghqDepth); err != nil {
+			return err
+		}
+	}
+	return ch.sessions.OpenSession(ctx, dest, dest)
+}
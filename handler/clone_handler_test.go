@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	testutils "github.com/TlexCypher/my-tmux-sessionizer/test_utils"
+)
+
+func TestCloneHandler_Clone_ClonesIntoHostOwnerRepoAndOpensSession(t *testing.T) {
+	t.Parallel()
+
+	origin := filepath.Join(t.TempDir(), "owner", "repo")
+	testutils.InitGitRepo(t, origin)
+	bare := origin + ".git"
+	testutils.Git(t, origin, "clone", "-q", "--bare", origin, bare)
+
+	cloneRoot := t.TempDir()
+	configFileAbs := writeConfig(t, io.ConfigPrefix)
+	sessions := newFakeSessionHandler()
+	ch := NewCloneHandler(&io.Config{CloneRoot: types.NewString(cloneRoot)}, NewProjectHandler(configFileAbs), sessions)
+
+	if err := ch.Clone(t.Context(), "file://"+bare); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	dest := filepath.Join(cloneRoot, "localhost", "owner", "repo")
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err != nil {
+		t.Errorf("expected repository at %s, got %v", dest, err)
+	}
+	if want := io.ConfigPrefix + "\n" + io.RootPrefix + "3," + cloneRoot; readConfig(t, configFileAbs) != want {
+		t.Errorf("expected config %q, got %q", want, readConfig(t, configFileAbs))
+	}
+	if _, opened := sessions.opened[dest]; !opened {
+		t.Errorf("expected session of %s to be opened, got %v", dest, sessions.opened)
+	}
+
+	// Cloning again only opens the session, so the registration stays single.
+	if err := ch.Clone(t.Context(), bare); err != nil {
+		t.Fatalf("expected no error on second clone, got %v", err)
+	}
+	if want := io.ConfigPrefix + "\n" + io.RootPrefix + "3," + cloneRoot; readConfig(t, configFileAbs) != want {
+		t.Errorf("expected config to stay %q, got %q", want, readConfig(t, configFileAbs))
+	}
+}
+
+func TestCloneHandler_Clone_SkipsRegisteringUnderRegisteredRoot(t *testing.T) {
+	t.Parallel()
+
+	origin := filepath.Join(t.TempDir(), "owner", "repo")
+	testutils.InitGitRepo(t, origin)
+
+	cloneRoot := t.TempDir()
+	content := io.RootPrefix + "3," + cloneRoot
+	configFileAbs := writeConfig(t, content)
+	config := &io.Config{CloneRoot: types.NewString(cloneRoot), Registered: []types.String{types.NewString(cloneRoot)}}
+	ch := NewCloneHandler(config, NewProjectHandler(configFileAbs), newFakeSessionHandler())
+
+	if err := ch.Clone(t.Context(), origin); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := readConfig(t, configFileAbs); got != content {
+		t.Errorf("expected config to stay %q, got %q", content, got)
+	}
+}
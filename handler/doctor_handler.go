@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/validate"
+)
+
+var (
+	ErrUnhealthy = errors.New("doctor found problems")
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Finding is the outcome of a single check. Fix tells the user what to do
+// about a warning or failure.
+type Finding struct {
+	Status  Status
+	Check   string
+	Message string
+	Fix     string
+}
+
+type DoctorHandler struct {
+	configFile  iohelper.ConfigFile
+	filer       *iohelper.Filer
+	tmux        *tmux.Tmux
+	transformer *session.Transformer
+}
+
+func NewDoctorHandler(
+	configFile iohelper.ConfigFile,
+	filer *iohelper.Filer,
+	tmux *tmux.Tmux,
+	transformer *session.Transformer,
+) *DoctorHandler {
+	return &DoctorHandler{
+		configFile:  configFile,
+		filer:       filer,
+		tmux:        tmux,
+		transformer: transformer,
+	}
+}
+
+// Diagnose checks the tools tmux-sessionizer runs, the tmux server, the
+// config file and the projects it lists. It never stops at the first
+// problem, so a single run shows everything that needs fixing.
+func (dh *DoctorHandler) Diagnose(ctx context.Context) []Finding {
+	findings := []Finding{}
+
+	tmuxFinding := checkTool(ctx, "tmux", "-V", "install tmux, see https://github.com/tmux/tmux/wiki/Installing")
+	findings = append(findings,
+		tmuxFinding,
+		checkTool(ctx, "fzf", "--version", "install fzf, see https://github.com/junegunn/fzf#installation"),
+	)
+
+	running := make(map[types.String]*session.Session)
+	if tmuxFinding.Status == StatusOK {
+		finding := dh.checkServer(ctx)
+		findings = append(findings, finding)
+		if finding.Status == StatusOK {
+			if sessions, err := dh.tmux.GatherExistingSessions(ctx); err == nil {
+				running = sessions
+			}
+		}
+	}
+
+	config, finding := dh.checkConfig(ctx)
+	findings = append(findings, finding)
+	if config == nil {
+		return findings
+	}
+
+	findings = append(findings, checkSkipped(config)...)
+	findings = append(findings, checkRoots(config, dh.configFile.Path)...)
+	// The manager must not touch running, which is compared against below.
+	manager := session.NewSessionManager(make(map[types.String]*session.Session), dh.transformer).WithAliases(config.Aliases)
+	return append(findings, checkSessionNames(config, manager, running, dh.configFile.Path)...)
+}
+
+// Report prints findings to w, and fails when any of them failed.
+func Report(w io.Writer, findings []Finding) error {
+	failed := 0
+	for _, f := range findings {
+		fmt.Fprintf(w, "[%s] %s: %s\n", f.Status, f.Check, f.Message)
+		if f.Status != StatusOK && len(f.Fix) > 0 {
+			fmt.Fprintf(w, "       fix: %s\n", f.Fix)
+		}
+		if f.Status == StatusFail {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed:%w", failed, ErrUnhealthy)
+	}
+	return nil
+}
+
+func checkTool(ctx context.Context, name string, versionFlag string, fix string) Finding {
+	version, err := command.Output(ctx, name, versionFlag)
+	if err != nil {
+		return Finding{Status: StatusFail, Check: name, Message: err.Error(), Fix: fix}
+	}
+
+	// fzf prints its version followed by the build, e.g. "0.44.1 (d7d2ac3)".
+	version, _, _ = strings.Cut(version, "\n")
+	return Finding{Status: StatusOK, Check: name, Message: version}
+}
+
+func (dh *DoctorHandler) checkServer(ctx context.Context) Finding {
+	err := dh.tmux.Ping(ctx)
+	switch {
+	case err == nil:
+		return Finding{Status: StatusOK, Check: "tmux server", Message: "reachable"}
+	case dh.tmux.IsInSession():
+		return Finding{
+			Status:  StatusFail,
+			Check:   "tmux server",
+			Message: err.Error(),
+			Fix:     "$TMUX is set but its server does not answer; make sure $TMUX_TMPDIR and the -L/-S socket match the running server",
+		}
+	default:
+		return Finding{
+			Status:  StatusWarn,
+			Check:   "tmux server",
+			Message: "no server is running",
+			Fix:     "nothing to do if tmux is not started yet, the first session starts one",
+		}
+	}
+}
+
+// checkConfig returns the parsed config, or nil when the checks depending on
+// it cannot run.
+func (dh *DoctorHandler) checkConfig(ctx context.Context) (*iohelper.Config, Finding) {
+	check := fmt.Sprintf("config %s (from %s)", dh.configFile.Path, dh.configFile.Source)
+	if err := dh.filer.Exists(dh.configFile.Path); err != nil {
+		return nil, Finding{Status: StatusFail, Check: check, Message: "does not exist", Fix: "run `tmux-sessionizer init`"}
+	}
+	if err := validate.ValidateConfig(dh.configFile.Path); err != nil {
+		return nil, Finding{Status: StatusFail, Check: check, Message: err.Error(), Fix: "run `tmux-sessionizer init` or add a " + iohelper.ConfigPrefix + " line"}
+	}
+
+	config, err := iohelper.NewConfigParser().ReadConfig(ctx, dh.filer, dh.configFile.Path)
+	if err != nil {
+		return nil, Finding{Status: StatusFail, Check: check, Message: err.Error(), Fix: "run `tmux-sessionizer config check` to see every problem with its line"}
+	}
+	return config, Finding{Status: StatusOK, Check: check, Message: fmt.Sprintf("%d project(s) found", len(config.Projects))}
+}
+
+// checkSkipped reports the lines of the config file that were left out.
+func checkSkipped(config *iohelper.Config) []Finding {
+	findings := []Finding{}
+	for _, err := range config.Skipped {
+		findings = append(findings, Finding{
+			Status:  StatusWarn,
+			Check:   "config line",
+			Message: err.Error() + ", it is skipped",
+			Fix:     "run `tmux-sessionizer config check` to see every problem with its line",
+		})
+	}
+	return findings
+}
+
+func checkRoots(config *iohelper.Config, configFileAbs string) []Finding {
+	findings := []Finding{}
+	for _, root := range config.Registered {
+		findings = append(findings, checkRegistered("root", root, configFileAbs))
+	}
+	for _, project := range config.RegisteredProjects {
+		findings = append(findings, checkRegistered("project", project, configFileAbs))
+	}
+	for _, slow := range config.SlowRoots {
+		findings = append(findings, Finding{
+			Status:  StatusWarn,
+			Check:   "discovery " + slow.Value(),
+			Message: fmt.Sprintf("took longer than %s, its projects are skipped", config.DiscoveryTimeout),
+			Fix:     fmt.Sprintf("check whether it lives on a slow or hung mount, or raise %s", iohelper.DiscoveryTimeoutPrefix),
+		})
+	}
+	return findings
+}
+
+// checkRegistered checks a root or project entry of the config file.
+func checkRegistered(kind string, path types.String, configFileAbs string) Finding {
+	check := kind + " " + path.Value()
+	info, err := os.Stat(path.Value())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return Finding{
+			Status:  StatusWarn,
+			Check:   check,
+			Message: "does not exist, it is skipped",
+			Fix:     "create it or remove it from " + configFileAbs,
+		}
+	case err != nil:
+		return Finding{Status: StatusFail, Check: check, Message: err.Error(), Fix: "fix its permissions, e.g. chmod u+rx " + path.Value()}
+	case !info.IsDir():
+		return Finding{
+			Status:  StatusFail,
+			Check:   check,
+			Message: "is not a directory",
+			Fix:     "register the directory holding it instead",
+		}
+	}
+	if _, err := os.ReadDir(path.Value()); err != nil {
+		return Finding{Status: StatusFail, Check: check, Message: err.Error(), Fix: "fix its permissions, e.g. chmod u+rx " + path.Value()}
+	}
+	return Finding{Status: StatusOK, Check: check, Message: "readable"}
+}
+
+// checkSessionNames finds projects whose sessions would get the same name,
+// and running sessions of other directories already holding a project's name.
+// tmux refuses a second session with a name in use, so either breaks opening.
+func checkSessionNames(
+	config *iohelper.Config,
+	manager *session.SessionManager,
+	running map[types.String]*session.Session,
+	configFileAbs string,
+) []Finding {
+	names := []string{}
+	pathsOf := make(map[string][]string)
+	for _, project := range config.Projects {
+		sessionName := manager.NameOf(sessionNameOf(config, project.Value()), project.Value())
+		name := sessionName.Value()
+		if _, exists := pathsOf[name]; !exists {
+			names = append(names, name)
+		}
+		pathsOf[name] = append(pathsOf[name], project.Value())
+	}
+
+	runningPathOf := make(map[string]string, len(running))
+	for _, s := range running {
+		runningPathOf[s.Name.Value()] = s.ProjectPath.Value()
+	}
+
+	findings := []Finding{}
+	for _, name := range names {
+		paths := pathsOf[name]
+		if len(paths) > 1 {
+			findings = append(findings, Finding{
+				Status:  StatusFail,
+				Check:   "session " + name,
+				Message: "shared by " + strings.Join(paths, ", "),
+				Fix:     fmt.Sprintf("give all but one of them another name with %s<path>,<name> in %s", iohelper.AliasPrefix, configFileAbs),
+			})
+			continue
+		}
+		if path, exists := runningPathOf[name]; exists && path != paths[0] {
+			findings = append(findings, Finding{
+				Status:  StatusWarn,
+				Check:   "session " + name,
+				Message: fmt.Sprintf("already running in %s, so %s cannot be opened", path, paths[0]),
+				Fix:     fmt.Sprintf("rename the running session with `tmux-sessionizer rename %s <new-name>`", name),
+			})
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Status: StatusOK, Check: "session names", Message: "no collisions"})
+	}
+	return findings
+}
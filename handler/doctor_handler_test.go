@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func findingStatuses(findings []Finding) map[string]Status {
+	statuses := make(map[string]Status, len(findings))
+	for _, f := range findings {
+		statuses[f.Check] = f.Status
+	}
+	return statuses
+}
+
+func TestCheckRoots(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	config := &io.Config{
+		Registered: []types.String{
+			types.NewString(dir), types.NewString(file), types.NewString(missing),
+		},
+		SlowRoots:        []types.String{types.NewString("/mnt/nfs")},
+		DiscoveryTimeout: 10 * time.Second,
+	}
+
+	got := findingStatuses(checkRoots(config, "/home/user/.tmux-sessionizer"))
+
+	want := map[string]Status{
+		"root " + dir:        StatusOK,
+		"root " + file:       StatusFail,
+		"root " + missing:    StatusWarn,
+		"discovery /mnt/nfs": StatusWarn,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("checkRoots() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckSkipped(t *testing.T) {
+	t.Parallel()
+
+	config := &io.Config{Skipped: []error{errors.New("/home/user/.tmux-sessionizer:3:\"post-kill\":unknown hook event")}}
+
+	got := checkSkipped(config)
+
+	want := []Finding{{
+		Status:  StatusWarn,
+		Check:   "config line",
+		Message: "/home/user/.tmux-sessionizer:3:\"post-kill\":unknown hook event, it is skipped",
+		Fix:     "run `tmux-sessionizer config check` to see every problem with its line",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("checkSkipped() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckSessionNames(t *testing.T) {
+	t.Parallel()
+
+	transformer := session.NewTransformer().WithRule(session.NewTransformRule(
+		func(in string) string { return strings.ReplaceAll(in, ".", "_") },
+		func(in string) string { return strings.ReplaceAll(in, "_", ".") },
+	))
+	config := &io.Config{
+		Projects: []types.String{
+			types.NewString("/src/app.v2"),
+			types.NewString("/src/app_v2"),
+			types.NewString("/src/api"),
+			types.NewString("/src/web"),
+			types.NewString("/src/docs"),
+		},
+		Aliases: map[types.String]types.String{
+			types.NewString("/src/docs"): types.NewString("site"),
+		},
+	}
+	running := map[types.String]*session.Session{
+		types.NewString("/tmp/api"): session.NewSession(types.NewString("/src/api"), types.NewString("/tmp/api")),
+		types.NewString("/src/web"): session.NewSession(types.NewString("/src/web"), types.NewString("/src/web")),
+	}
+	manager := session.NewSessionManager(make(map[types.String]*session.Session), transformer).WithAliases(config.Aliases)
+
+	got := findingStatuses(checkSessionNames(config, manager, running, "/home/user/.tmux-sessionizer"))
+
+	want := map[string]Status{
+		"session /src/app_v2": StatusFail,
+		"session /src/api":    StatusWarn,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("checkSessionNames() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReport_FailsOnlyOnFailures(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := Report(&buf, []Finding{
+		{Status: StatusOK, Check: "tmux", Message: "tmux 3.3a"},
+		{Status: StatusWarn, Check: "tmux server", Message: "no server is running", Fix: "start tmux"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error for warnings, got %v", err)
+	}
+	want := "[ok] tmux: tmux 3.3a\n[warn] tmux server: no server is running\n       fix: start tmux\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("Report() mismatch (-want +got):\n%s", diff)
+	}
+
+	err = Report(&bytes.Buffer{}, []Finding{{Status: StatusFail, Check: "fzf", Message: "not found"}})
+	if !errors.Is(err, ErrUnhealthy) {
+		t.Errorf("expected ErrUnhealthy, got %v", err)
+	}
+}
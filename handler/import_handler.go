@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+var (
+	ErrUnknownImportSource = errors.New("unknown import source")
+)
+
+type ImportSource string
+
+const (
+	ImportZoxide ImportSource = "zoxide"
+	ImportGhq    ImportSource = "ghq"
+	ImportTmux   ImportSource = "tmux"
+
+	// ghqDepth is how deep repositories sit below a ghq root: <host>/<owner>/<repository>.
+	ghqDepth = 3
+)
+
+// ImportSources lists every source in the order they are documented.
+var ImportSources = []ImportSource{ImportZoxide, ImportGhq, ImportTmux}
+
+func ParseImportSource(raw string) (ImportSource, error) {
+	source := ImportSource(raw)
+	if !slices.Contains(ImportSources, source) {
+		return "", fmt.Errorf("%q:%w", raw, ErrUnknownImportSource)
+	}
+	return source, nil
+}
+
+// depth is the depth the directories of source are registered with: ghq
+// lists roots, the others list projects.
+func (s ImportSource) depth() int {
+	if s == ImportGhq {
+		return ghqDepth
+	}
+	return 0
+}
+
+type ImportHandler struct {
+	config   *iohelper.Config
+	projects *ProjectHandler
+	tmux     *tmux.Tmux
+}
+
+func NewImportHandler(config *iohelper.Config, projects *ProjectHandler, tmux *tmux.Tmux) *ImportHandler {
+	return &ImportHandler{
+		config:   config,
+		projects: projects,
+		tmux:     tmux,
+	}
+}
+
+// Import offers the directories source knows and tmux-sessionizer does not
+// for registration with fzf, and registers the picked ones. It returns them,
+// which is nothing when there was nothing to offer.
+func (ih *ImportHandler) Import(ctx context.Context, source ImportSource) ([]string, error) {
+	dirs, err := ih.Discover(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	fzfCmd := command.NewFzfCommand(ctx, "-m", "--prompt", fmt.Sprintf("import from %s> ", source))
+	for _, dir := range dirs {
+		fzfCmd.InBuf().WriteString(dir + "\n")
+	}
+	if err := fzfCmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to pick directories to import with fzf:%w", err)
+	}
+
+	picked := pickedPaths(fzfCmd.OutBuf().String())
+	for _, dir := range picked {
+		if err := ih.register(ctx, source, dir); err != nil {
+			return nil, err
+		}
+	}
+	return picked, nil
+}
+
+func (ih *ImportHandler) register(ctx context.Context, source ImportSource, dirAbs string) error {
+	if depth := source.depth(); depth > 0 {
+		return ih.projects.RegisterRoot(ctx, dirAbs, depth)
+	}
+	return ih.projects.RegisterProject(ctx, dirAbs)
+}
+
+// Discover lists the directories of source worth registering, in the order
+// source gives them.
+func (ih *ImportHandler) Discover(ctx context.Context, source ImportSource) ([]string, error) {
+	var dirs []string
+	switch source {
+	case ImportZoxide:
+		out, err := command.Output(ctx, "zoxide", "query", "-ls")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zoxide database:%w", err)
+		}
+		dirs = parseZoxide(out)
+	case ImportGhq:
+		out, err := command.Output(ctx, "ghq", "root", "--all")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ghq roots:%w", err)
+		}
+		dirs = strings.Split(out, "\n")
+	case ImportTmux:
+		sessions, err := ih.tmux.GatherExistingSessions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sessions {
+			// A scratch directory goes away with its session.
+			if !s.Scratch {
+				dirs = append(dirs, s.ProjectPath.Value())
+			}
+		}
+		// A workspace session shares the directory of its first project.
+		slices.Sort(dirs)
+		dirs = slices.Compact(dirs)
+	default:
+		return nil, fmt.Errorf("%q:%w", source, ErrUnknownImportSource)
+	}
+	return importable(ih.config, source, dirs), nil
+}
+
+// parseZoxide reads `zoxide query -ls`, which prints the score before each
+// path, highest first.
+func parseZoxide(out string) []string {
+	dirs := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		_, dir, found := strings.Cut(strings.TrimSpace(line), " ")
+		if dir = strings.TrimSpace(dir); found && len(dir) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// importable drops what cannot or need not be registered: entries that are
+// not existing absolute directories, paths holding the config separator,
+// duplicates, and directories registered or already listed as projects.
+func importable(config *iohelper.Config, source ImportSource, dirs []string) []string {
+	known := make(map[string]struct{})
+	for _, paths := range [][]types.String{config.Registered, config.RegisteredProjects} {
+		for _, p := range paths {
+			known[p.Value()] = struct{}{}
+		}
+	}
+	// A root may well be a project of another root, so only projects are
+	// skipped when they can be picked already.
+	if source.depth() == 0 {
+		for _, p := range config.Projects {
+			known[p.Value()] = struct{}{}
+		}
+	}
+
+	kept := []string{}
+	for _, dir := range dirs {
+		if _, exists := known[dir]; exists || !filepath.IsAbs(dir) || strings.Contains(dir, ",") {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		known[dir] = struct{}{}
+		kept = append(kept, dir)
+	}
+	return kept
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseImportSource_RejectsUnknownSource(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseImportSource("autojump"); !errors.Is(err, ErrUnknownImportSource) {
+		t.Errorf("expected ErrUnknownImportSource, got %v", err)
+	}
+}
+
+func Test_parseZoxide(t *testing.T) {
+	t.Parallel()
+
+	out := "  52.5 /home/me/src/app\n   8.0 /home/me/my notes\n\n0.25 /tmp"
+	want := []string{"/home/me/src/app", "/home/me/my notes", "/tmp"}
+	if diff := cmp.Diff(want, parseZoxide(out)); diff != "" {
+		t.Errorf("parseZoxide() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_importable(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	root, project, registered, fresh := filepath.Join(dir, "root"), filepath.Join(dir, "root", "project"), filepath.Join(dir, "registered"), filepath.Join(dir, "fresh")
+	for _, d := range []string{project, registered, fresh, filepath.Join(dir, "a,b")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := &io.Config{
+		Registered:         []types.String{types.NewString(root)},
+		RegisteredProjects: []types.String{types.NewString(registered)},
+		Projects:           []types.String{types.NewString(project), types.NewString(registered)},
+	}
+	dirs := []string{fresh, root, project, registered, fresh, file, filepath.Join(dir, "missing"), filepath.Join(dir, "a,b"), "relative"}
+
+	tests := []struct {
+		name   string
+		source ImportSource
+		want   []string
+	}{
+		{
+			name:   "projects already listed are skipped",
+			source: ImportZoxide,
+			want:   []string{fresh},
+		},
+		{
+			name:   "a project may become a root",
+			source: ImportGhq,
+			want:   []string{fresh, project},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, importable(config, tt.source, dirs)); diff != "" {
+				t.Errorf("importable() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
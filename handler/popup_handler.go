@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+)
+
+// KeyBinding binds Key to a popup running tmux-sessionizer with Args.
+type KeyBinding struct {
+	Key  string
+	Args []string
+}
+
+// PopupHandler runs tmux-sessionizer again in a tmux popup, which closes as
+// soon as a session was switched to, and writes the key bindings opening it.
+type PopupHandler struct {
+	tmux *tmux.Tmux
+	// self starts tmux-sessionizer again: the executable and the flags every
+	// run needs, such as the config file, which the popup cannot resolve the
+	// same way since it gets the environment of the tmux server.
+	self []string
+}
+
+func NewPopupHandler(tmux *tmux.Tmux, self []string) *PopupHandler {
+	return &PopupHandler{
+		tmux: tmux,
+		self: self,
+	}
+}
+
+// Open runs tmux-sessionizer with args in popup.
+func (ph *PopupHandler) Open(ctx context.Context, popup tmux.Popup, args []string) error {
+	if !ph.tmux.IsInSession() {
+		return ErrNotInSession
+	}
+	if err := ph.tmux.DisplayPopup(ctx, popup, append(slices.Clone(ph.self), args...)); err != nil {
+		return fmt.Errorf("failed to open popup:%w", err)
+	}
+	return nil
+}
+
+// WriteKeyBindings writes a bind-key line for every binding, ready to be
+// sourced by tmux. The popup is shown on the client the key was pressed in.
+func (ph *PopupHandler) WriteKeyBindings(w io.Writer, popup tmux.Popup, bindings []KeyBinding) error {
+	for _, b := range bindings {
+		args := append(slices.Clone(ph.self),
+			"popup",
+			// run-shell expands the format before the command runs.
+			"--client", "#{client_name}",
+			"--width", popup.Width,
+			"--height", popup.Height,
+			"--x", popup.X,
+			"--y", popup.Y,
+		)
+		// -b keeps tmux responsive while the popup is being opened.
+		if _, err := fmt.Fprintf(w, "bind-key %s run-shell -b %s\n", b.Key, tmux.Quote(tmux.ShellJoin(append(args, b.Args...)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
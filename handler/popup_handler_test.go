@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPopupHandler_WriteKeyBindings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		self     []string
+		bindings []KeyBinding
+		want     string
+	}{
+		{
+			name:     "picker",
+			self:     []string{"/bin/tmux-sessionizer", "--config", "/home/me/config"},
+			bindings: []KeyBinding{{Key: "f"}},
+			want:     `bind-key f run-shell -b "'/bin/tmux-sessionizer' '--config' '/home/me/config' 'popup' '--client' '#{client_name}' '--width' '80%' '--height' '50%' '--x' 'C' '--y' 'S'"` + "\n",
+		},
+		{
+			name:     "command and quoting",
+			self:     []string{"/bin/tmux-sessionizer", "--config", `/home/me/it's "$HOME"`},
+			bindings: []KeyBinding{{Key: "F", Args: []string{"window"}}},
+			want:     `bind-key F run-shell -b "'/bin/tmux-sessionizer' '--config' '/home/me/it'\\''s \"\$HOME\"' 'popup' '--client' '#{client_name}' '--width' '80%' '--height' '50%' '--x' 'C' '--y' 'S' 'window'"` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			popup := tmux.Popup{Width: "80%", Height: "50%", X: "C", Y: "S"}
+			if err := NewPopupHandler(tmux.NewTmux(), tt.self).WriteKeyBindings(&buf, popup, tt.bindings); err != nil {
+				t.Fatalf("WriteKeyBindings() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("WriteKeyBindings() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -5,12 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
 )
 
-const configFilePermission = 0o644
+const (
+	configFilePermission = 0o644
+	dirPermission        = 0o755
+)
 
 type ProjectHandler struct {
 	configFile string
@@ -30,7 +35,11 @@ func (ph *ProjectHandler) Init(ctx context.Context, configFileAbs string) error
 		return fmt.Errorf("failed to get status of config file:%w", err)
 	}
 
-	// If configfile does not exist, create it.
+	// If configfile does not exist, create it. The XDG location usually
+	// needs its directory created first.
+	if err := os.MkdirAll(filepath.Dir(configFileAbs), dirPermission); err != nil {
+		return fmt.Errorf("failed to create directory of config file:%w", err)
+	}
 	f, err := os.Create(configFileAbs)
 	if err != nil {
 		return fmt.Errorf("failed to create config file of tmux-sessionizer:%w", err)
@@ -44,7 +53,27 @@ func (ph *ProjectHandler) Init(ctx context.Context, configFileAbs string) error
 	return nil
 }
 
+// Register registers projectPathAbs as a root whose immediate subdirectories
+// are projects.
 func (ph *ProjectHandler) Register(ctx context.Context, projectPathAbs string) error {
+	return ph.register(ctx, io.ConfigPrefix, projectPathAbs)
+}
+
+// RegisterRoot registers rootAbs as a root whose directories up to depth
+// levels below are projects.
+func (ph *ProjectHandler) RegisterRoot(ctx context.Context, rootAbs string, depth int) error {
+	if depth == 1 {
+		return ph.Register(ctx, rootAbs)
+	}
+	return ph.register(ctx, fmt.Sprintf("%s%d,", io.RootPrefix, depth), rootAbs)
+}
+
+// RegisterProject registers projectPathAbs as a project itself.
+func (ph *ProjectHandler) RegisterProject(ctx context.Context, projectPathAbs string) error {
+	return ph.register(ctx, io.ProjectPrefix, projectPathAbs)
+}
+
+func (ph *ProjectHandler) register(ctx context.Context, prefix string, projectPathAbs string) error {
 	// NOTE: tmux-sessionizer only allows to handle directory as a project, so at first we need to check the status.
 	fi, err := os.Stat(projectPathAbs)
 	if err != nil {
@@ -54,66 +83,135 @@ func (ph *ProjectHandler) Register(ctx context.Context, projectPathAbs string) e
 		return errors.New("tmux-sessionizer does not allow to register file as a project")
 	}
 
-	// read config file
-	f, err := os.OpenFile(ph.configFile, os.O_APPEND|os.O_RDWR, configFilePermission)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+	// The config file holds other keys besides default=, so the project has to
+	// be appended to the line of its kind instead of the end of the file.
+	if err := ph.rewrite(ctx, func(lines []string) ([]string, error) {
+		return ph.appendEntry(lines, prefix, projectPathAbs)
+	}); err != nil {
+		return fmt.Errorf("failed to append project to configFile:%w", err)
 	}
-	defer f.Close()
+	return nil
+}
 
-	// The config file may end with \n, so we need to truncate new line.
-	if err := ph.truncate(ctx, f); err != nil {
-		return fmt.Errorf("failed to truncate last /\n:%w", err)
+// RegisterRootOnce registers dirAbs as a root with depth unless config already
+// has it, and keeps config in sync so later calls in the same run see the
+// registration.
+func (ph *ProjectHandler) RegisterRootOnce(ctx context.Context, config *io.Config, dirAbs string, depth int) error {
+	for _, registered := range config.Registered {
+		if registered.Value() == dirAbs {
+			return nil
+		}
 	}
 
-	// Append new directory to config file. To improve performance, using strings.Builder
-	if err := ph.appendProject(ctx, f, projectPathAbs); err != nil {
-		return fmt.Errorf("failed to append project to configFile:%w", err)
+	if err := ph.RegisterRoot(ctx, dirAbs, depth); err != nil {
+		return err
 	}
+	config.Registered = append(config.Registered, types.NewString(dirAbs))
 	return nil
 }
 
-func (ph *ProjectHandler) truncate(_ context.Context, f *os.File) error {
-	info, err := f.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to get status of file: %w", err)
-	}
-	if size := info.Size(); size > 0 {
-		last := make([]byte, 1)
-		if _, err := f.ReadAt(last, size-1); err != nil {
-			return fmt.Errorf("failed to read last byte:%w", err)
+// RegisterProjectOnce registers projectPathAbs as a project unless config
+// already has it, and keeps config in sync like RegisterRootOnce does.
+func (ph *ProjectHandler) RegisterProjectOnce(ctx context.Context, config *io.Config, projectPathAbs string) error {
+	for _, registered := range config.RegisteredProjects {
+		if registered.Value() == projectPathAbs {
+			return nil
 		}
-		if last[0] == '\n' {
-			if err := f.Truncate(size - 1); err != nil {
-				return fmt.Errorf("failed to truncate last trailing new line:%w", err)
+	}
+
+	if err := ph.RegisterProject(ctx, projectPathAbs); err != nil {
+		return err
+	}
+	config.RegisteredProjects = append(config.RegisteredProjects, types.NewString(projectPathAbs))
+	return nil
+}
+
+// Alias records name as the session name of projectPathAbs, replacing any
+// alias recorded for the same path before.
+func (ph *ProjectHandler) Alias(ctx context.Context, projectPathAbs string, name string) error {
+	if strings.Contains(projectPathAbs, ",") || strings.Contains(name, ",") {
+		return fmt.Errorf("alias %s for %s must not contain ',', the config file separator", name, projectPathAbs)
+	}
+
+	if err := ph.rewrite(ctx, func(lines []string) ([]string, error) {
+		aliasOf := io.AliasPrefix + projectPathAbs + ","
+		kept := make([]string, 0, len(lines)+1)
+		for _, line := range lines {
+			if !strings.HasPrefix(line, aliasOf) {
+				kept = append(kept, line)
 			}
 		}
+		return append(kept, aliasOf+name), nil
+	}); err != nil {
+		return fmt.Errorf("failed to record alias %s for %s:%w", name, projectPathAbs, err)
 	}
 	return nil
 }
 
-func (ph *ProjectHandler) appendProject(_ context.Context, f *os.File, projectPath string) error {
-	// Stat the handle, not the path: the size must reflect the file this
-	// handle just truncated, even if the path was swapped out meanwhile.
-	fi, err := f.Stat()
+// rewrite applies edit to the lines of the config file and writes them back.
+// The config file may end with \n, which is dropped like it always has been.
+func (ph *ProjectHandler) rewrite(_ context.Context, edit func(lines []string) ([]string, error)) error {
+	b, err := os.ReadFile(ph.configFile)
 	if err != nil {
-		return fmt.Errorf("failed to get status of project config file:%w", err)
+		return fmt.Errorf("failed to read config file:%w", err)
 	}
 
-	// If any projects has not been registered to config file, we don't need to add ",".
-	var builder strings.Builder
-	if fi.Size() != int64(len(io.ConfigPrefix)) {
-		if _, err := builder.WriteString(","); err != nil {
-			return fmt.Errorf("failed to write ,:%w", err)
-		}
+	lines, err := edit(strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"))
+	if err != nil {
+		return err
 	}
 
-	_, err = builder.WriteString(projectPath)
+	if err := ph.replace([]byte(strings.Join(lines, "\n"))); err != nil {
+		return fmt.Errorf("failed to write config file:%w", err)
+	}
+	return nil
+}
+
+// replace writes b to a temporary file next to the config file and renames it
+// into place, so a crash or a full disk never leaves the config file half
+// written. A config file symlinked from a dotfiles repository stays a symlink.
+func (ph *ProjectHandler) replace(b []byte) error {
+	path, err := filepath.EvalSymlinks(ph.configFile)
 	if err != nil {
-		return fmt.Errorf("failed to write %s:%w", projectPath, err)
+		return err
 	}
-	if _, err = f.WriteString(builder.String()); err != nil {
-		return fmt.Errorf("failed to register new project to config file:%w", err)
+	mode := os.FileMode(configFilePermission)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
 	}
-	return nil
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// appendEntry appends path to the first line starting with prefix. Without
+// such a line a new one is added.
+func (ph *ProjectHandler) appendEntry(lines []string, prefix string, path string) ([]string, error) {
+	for i, line := range lines {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		// If any projects has not been registered to config file, we don't need to add ",".
+		if len(strings.TrimSpace(strings.TrimPrefix(line, prefix))) != 0 {
+			line += ","
+		}
+		lines[i] = line + path
+		return lines, nil
+	}
+	return append(lines, prefix+path), nil
 }
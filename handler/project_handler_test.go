@@ -131,3 +131,91 @@ func TestProjectHandler_Register_RejectsRegularFile(t *testing.T) {
 		t.Errorf("expected config to stay %q, got %q", io.ConfigPrefix, got)
 	}
 }
+
+func TestProjectHandler_Register_ReplacesSymlinkedConfigTarget(t *testing.T) {
+	t.Parallel()
+
+	target := writeConfig(t, io.ConfigPrefix)
+	configFileAbs := filepath.Join(t.TempDir(), "config")
+	if err := os.Symlink(target, configFileAbs); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	ph := NewProjectHandler(configFileAbs)
+
+	if err := ph.Register(t.Context(), project); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if info, err := os.Lstat(configFileAbs); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected config file to stay a symlink, got %v, %v", info, err)
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected config file to keep mode 0600, got %v, %v", info, err)
+	}
+	if got, want := readConfig(t, target), io.ConfigPrefix+project; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 1 {
+		t.Errorf("expected no temporary file to be left, got %v", entries)
+	}
+}
+
+func TestProjectHandler_Alias_ReplacesPreviousAliasOfSameProject(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfig(t, io.ConfigPrefix+"/home/user/src\n"+io.AliasPrefix+"/home/user/src/app,old\n")
+	ph := NewProjectHandler(configFileAbs)
+
+	if err := ph.Alias(t.Context(), "/home/user/src/app", "new"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := io.ConfigPrefix + "/home/user/src\n" + io.AliasPrefix + "/home/user/src/app,new"
+	if got := readConfig(t, configFileAbs); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestProjectHandler_Register_AppendsToDefaultLineWhenAliasesFollow(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfig(t, io.ConfigPrefix+"/home/user/src\n"+io.AliasPrefix+"/home/user/src/app,app")
+	project := t.TempDir()
+	ph := NewProjectHandler(configFileAbs)
+
+	if err := ph.Register(t.Context(), project); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := io.ConfigPrefix + "/home/user/src," + project + "\n" + io.AliasPrefix + "/home/user/src/app,app"
+	if got := readConfig(t, configFileAbs); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestProjectHandler_RegisterRootAndProject_AppendEntryLines(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfig(t, io.ConfigPrefix)
+	first, second, project := t.TempDir(), t.TempDir(), t.TempDir()
+	ph := NewProjectHandler(configFileAbs)
+
+	if err := ph.RegisterRoot(t.Context(), first, 2); err != nil {
+		t.Fatalf("expected no error on first root, got %v", err)
+	}
+	if err := ph.RegisterProject(t.Context(), project); err != nil {
+		t.Fatalf("expected no error on project, got %v", err)
+	}
+	// Only a root of the same depth shares the line.
+	if err := ph.RegisterRoot(t.Context(), second, 2); err != nil {
+		t.Fatalf("expected no error on second root, got %v", err)
+	}
+
+	want := io.ConfigPrefix + "\n" +
+		io.RootPrefix + "2," + first + "," + second + "\n" +
+		io.ProjectPrefix + project
+	if got := readConfig(t, configFileAbs); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
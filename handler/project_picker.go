@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+// PickerAction is what to do with the entry picked in ProjectPicker.
+type PickerAction string
+
+const (
+	// ActionOpen attaches to the session of the entry, creating it if needed.
+	ActionOpen   PickerAction = "open"
+	ActionKill   PickerAction = "kill"
+	ActionRename PickerAction = "rename"
+	// ActionWindow opens the entry as a window of the current session.
+	ActionWindow PickerAction = "window"
+	// ActionEdit opens the entry in $EDITOR.
+	ActionEdit PickerAction = "edit"
+)
+
+// pickerKeys maps the keys fzf is told to --expect to their action; enter
+// prints no key and opens.
+var pickerKeys = []struct {
+	key    string
+	action PickerAction
+}{
+	{key: "ctrl-x", action: ActionKill},
+	{key: "ctrl-r", action: ActionRename},
+	{key: "ctrl-w", action: ActionWindow},
+	{key: "ctrl-o", action: ActionEdit},
+}
+
+// Reopens tells whether the picker is shown again after a, because a only
+// changed the sessions rather than left the picker for one.
+func (a PickerAction) Reopens() bool {
+	return a == ActionKill || a == ActionRename
+}
+
+// pickerOpts make fzf show and search the tag and label after the tab of each
+// line, while the path before it is what gets printed when picked, after the
+// key pressed.
+func pickerOpts() []string {
+	keys, help := []string{}, []string{"enter: open"}
+	for _, k := range pickerKeys {
+		keys = append(keys, k.key)
+		help = append(help, fmt.Sprintf("%s: %s", k.key, k.action))
+	}
+	return []string{
+		"--delimiter", "\t", "--with-nth", "2..", "--ansi",
+		"--expect", strings.Join(keys, ","),
+		"--header", strings.Join(help, ", "),
+	}
+}
+
+// parsePick reads what fzf printed with --expect: the key pressed, empty for
+// enter, followed by the picked line.
+func parsePick(out string) (PickerAction, string) {
+	key, picked, _ := strings.Cut(out, "\n")
+	action := ActionOpen
+	for _, k := range pickerKeys {
+		if k.key == key {
+			action = k.action
+		}
+	}
+	return action, pickedPaths(picked)[0]
+}
+
+const (
+	// sessionTag and projectTag tell what picking an entry does: attach to a
+	// running session or create one for a project. Both are as long, so the
+	// labels line up.
+	sessionTag = "session"
+	projectTag = "project"
+)
+
+// ProjectPicker shows the running sessions and the projects in a single fzf
+// while the projects are still being discovered, like `fd | fzf` does: fzf
+// opens at once with the sessions and fills in as Add is called.
+type ProjectPicker struct {
+	fzf    *command.FzfCommand
+	in     io.WriteCloser
+	cancel context.CancelFunc
+	// running holds the sessions by project path; their projects are not
+	// listed twice.
+	running map[types.String]*session.Session
+	// closed is set once fzf stopped reading, e.g. because an entry was
+	// picked before discovery finished.
+	closed bool
+	// exited is closed once fzf exited, with err from waiting on it.
+	exited chan struct{}
+	err    error
+}
+
+// StartProjectPicker starts fzf, which lists running once AddSessions is
+// called, and no projects yet.
+func StartProjectPicker(ctx context.Context, running map[types.String]*session.Session) (*ProjectPicker, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	fzfCmd, in, err := command.NewFzfStreamCommand(ctx, pickerOpts()...)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to fzf:%w", err)
+	}
+	if err := fzfCmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start fzf:%w", err)
+	}
+
+	pp := &ProjectPicker{
+		fzf:     fzfCmd,
+		in:      in,
+		cancel:  cancel,
+		running: running,
+		exited:  make(chan struct{}),
+	}
+	go func() {
+		defer close(pp.exited)
+		pp.err = fzfCmd.Wait()
+	}()
+	return pp, nil
+}
+
+// AddSessions shows the running sessions in fzf. The session of a project is
+// labeled like the project, other sessions are named like tmux does. It is
+// meant for ConfigParser.OnRoots, so the sessions come before any project.
+func (pp *ProjectPicker) AddSessions(config *iohelper.Config) {
+	for _, s := range sortedSessions(slices.Collect(maps.Values(pp.running))) {
+		label := s.Name.Value()
+		switch {
+		case s.Scratch:
+			label += " (scratch)"
+		case len(s.Workspace) > 0:
+			label += " (workspace)"
+		case !s.Foreign:
+			label = config.LabelOf(s.ProjectPath.Value())
+		}
+		pp.write(s.Key(), sessionTag, label)
+	}
+}
+
+// Add shows project in fzf with the label config gives it, unless it has a
+// running session listed already. It is meant for ConfigParser.OnProject,
+// which calls it from a single goroutine.
+func (pp *ProjectPicker) Add(config *iohelper.Config, project types.String) {
+	if _, running := pp.running[project]; running {
+		return
+	}
+	pp.write(project, projectTag, config.LabelOf(project.Value()))
+}
+
+func (pp *ProjectPicker) write(path types.String, tag string, label string) {
+	if pp.closed {
+		return
+	}
+	if _, err := io.WriteString(pp.in, path.Value()+"\t"+tag+" "+label+"\n"); err != nil {
+		// fzf exited; whatever was picked is read by Pick.
+		pp.closed = true
+	}
+}
+
+// Exited is closed once fzf exited, picked or not, so discovery can stop
+// rather than make Pick wait for it.
+func (pp *ProjectPicker) Exited() <-chan struct{} {
+	return pp.exited
+}
+
+// Pick tells fzf every project is in and returns the path of the picked
+// session or project, with what to do with it.
+func (pp *ProjectPicker) Pick() (PickerAction, string, error) {
+	defer pp.cancel()
+
+	// Closing fails when fzf has already gone, which Wait reports better.
+	_ = pp.in.Close()
+	<-pp.exited
+	if pp.err != nil {
+		return "", "", fmt.Errorf("failed to grab project path with fzf: %w", pp.err)
+	}
+	action, picked := parsePick(pp.fzf.OutBuf().String())
+	return action, picked, nil
+}
+
+// Abort closes fzf without a pick, e.g. when discovery failed.
+func (pp *ProjectPicker) Abort() {
+	pp.cancel()
+	_ = pp.in.Close()
+	<-pp.exited
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+//nolint:paralleltest // t.Setenv is incompatible with t.Parallel.
+func TestProjectPicker_PicksBeforeEveryProjectIsAdded(t *testing.T) {
+	// A fake fzf picks the first candidate as soon as it arrives, like a user
+	// hitting enter while discovery still runs. Enter prints no key.
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho\nhead -n 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "fzf"), []byte(script), 0o755); err != nil { //nolint:gosec // the fake fzf must be executable.
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	picker, err := StartProjectPicker(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	config := &iohelper.Config{Label: iohelper.Label{Style: iohelper.LabelPath}}
+	picker.Add(config, types.NewString("/src/first"))
+	// Discovery is told fzf exited, so it can stop before every project is in.
+	select {
+	case <-picker.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected fzf to have exited after picking")
+	}
+	// Adding after fzf exited must neither fail nor block.
+	for range 10000 {
+		picker.Add(config, types.NewString("/src/later"))
+	}
+
+	action, got, err := picker.Pick()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "/src/first"; action != ActionOpen || got != want {
+		t.Errorf("expected %s %q, got %s %q", ActionOpen, want, action, got)
+	}
+}
+
+//nolint:paralleltest // t.Setenv is incompatible with t.Parallel.
+func TestProjectPicker_ListsSessionsBeforeProjects(t *testing.T) {
+	// A fake fzf records every candidate and kills the first one.
+	bin := t.TempDir()
+	candidates := filepath.Join(t.TempDir(), "candidates")
+	script := "#!/bin/sh\ncat > \"$CANDIDATES\"\necho ctrl-x\nhead -n 1 \"$CANDIDATES\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "fzf"), []byte(script), 0o755); err != nil { //nolint:gosec // the fake fzf must be executable.
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CANDIDATES", candidates)
+
+	scratch := session.NewSession(types.NewString("scratch-1"), types.NewString("/tmp/scratch-1"))
+	scratch.Scratch = true
+	// The workspace session starts in /src/b, which has a session of its own.
+	workspace := session.NewSession(types.NewString("micro"), types.NewString("/src/b"))
+	workspace.Workspace = "micro"
+	// A session opened with `tmux new` in /src/b is listed apart from it too.
+	foreign := session.NewSession(types.NewString("notes"), types.NewString("/src/b"))
+	foreign.Foreign = true
+	running := map[types.String]*session.Session{
+		workspace.Key():                   workspace,
+		foreign.Key():                     foreign,
+		types.NewString("/tmp/scratch-1"): scratch,
+		types.NewString("/src/b"):         session.NewSession(types.NewString("b"), types.NewString("/src/b")),
+		types.NewString("/elsewhere/a"):   session.NewSession(types.NewString("a"), types.NewString("/elsewhere/a")),
+	}
+	picker, err := StartProjectPicker(t.Context(), running)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	config := &iohelper.Config{
+		Label:      iohelper.Label{Style: iohelper.LabelRelative},
+		Registered: []types.String{types.NewString("/src")},
+	}
+	picker.AddSessions(config)
+	for _, p := range []string{"/src/a", "/src/b", "/src/c"} {
+		picker.Add(config, types.NewString(p))
+	}
+
+	action, got, err := picker.Pick()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "/elsewhere/a"; action != ActionKill || got != want {
+		t.Errorf("expected %s %q, got %s %q", ActionKill, want, action, got)
+	}
+
+	b, err := os.ReadFile(candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"/elsewhere/a\tsession /elsewhere/a",
+		"/src/b\tsession b",
+		"session:notes\tsession notes",
+		"workspace:micro\tsession micro (workspace)",
+		"/tmp/scratch-1\tsession scratch-1 (scratch)",
+		"/src/a\tproject a",
+		"/src/c\tproject c",
+	}, "\n") + "\n"
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("candidates mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParsePick(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		out        string
+		wantAction PickerAction
+		wantPath   string
+	}{
+		{
+			name:       "enter",
+			out:        "\n/src/app\tproject /src/app\n",
+			wantAction: ActionOpen,
+			wantPath:   "/src/app",
+		},
+		{
+			name:       "key",
+			out:        "ctrl-w\n/src/app\tsession app\n",
+			wantAction: ActionWindow,
+			wantPath:   "/src/app",
+		},
+		{
+			name:       "unknown key opens",
+			out:        "ctrl-z\n/src/app\tproject /src/app\n",
+			wantAction: ActionOpen,
+			wantPath:   "/src/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			action, path := parsePick(tt.out)
+			if action != tt.wantAction || path != tt.wantPath {
+				t.Errorf("parsePick() = %s %q, want %s %q", action, path, tt.wantAction, tt.wantPath)
+			}
+		})
+	}
+}
@@ -1,27 +1,63 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/hook"
 	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
 	"golang.org/x/sync/errgroup"
 )
 
+var (
+	ErrNoSuchWorkspace   = errors.New("no such workspace in config file")
+	ErrNotInSession      = errors.New("not inside a tmux session")
+	ErrNoEditor          = errors.New("$EDITOR is not set")
+	ErrUnsupportedAction = errors.New("unsupported picker action")
+)
+
+const (
+	// scratchDirPrefix starts the name of every scratch directory in os.TempDir.
+	scratchDirPrefix = "tmux-sessionizer-scratch-"
+	scratchLabel     = "\t(scratch)"
+
+	// fzf exits with fzfNoMatch when enter is pressed on nothing, and with
+	// fzfInterrupted when cancelled.
+	fzfNoMatch     = 1
+	fzfInterrupted = 130
+)
+
 type ISessionHandler interface {
-	NewSession(ctx context.Context) error
+	OpenProject(ctx context.Context, rawPath string) error
+	Act(ctx context.Context, action PickerAction, rawPath string) error
+	OpenWindow(ctx context.Context, rawPath string) error
+	AskSessionName(ctx context.Context, rawPath string) (string, error)
 	GrabExistingSession(ctx context.Context) error
 	DeleteSessions(ctx context.Context) error
+	RenameSession(ctx context.Context, target string, rawName string) (*session.Session, error)
+	OpenSession(ctx context.Context, rawName string, rawPath string) error
+	KillSession(ctx context.Context, rawPath string) error
+	NewScratchSession(ctx context.Context, rawName string) error
+	Prune(ctx context.Context) ([]string, error)
+	OpenWorkspace(ctx context.Context, name string, windows bool) error
 }
 
 type SessionHandler struct {
 	config  *iohelper.Config
 	manager *session.SessionManager
 	tmux    *tmux.Tmux
+	hooks   *hook.Runner
 }
 
 func NewSessionHandler(config *iohelper.Config, manager *session.SessionManager, tmux *tmux.Tmux) ISessionHandler {
@@ -29,83 +65,428 @@ func NewSessionHandler(config *iohelper.Config, manager *session.SessionManager,
 		config:  config,
 		manager: manager,
 		tmux:    tmux,
+		hooks:   hook.NewRunner(config.Hooks, config.HookTimeout),
 	}
 }
 
-func (sh *SessionHandler) NewSession(ctx context.Context) error {
-	/*build fzf input and build hashmap to retrieve filepath from entry's name.*/
-	fzfCmd := command.NewFzfCommand(ctx)
-	for _, project := range sh.config.Projects {
-		fzfCmd.InBuf().WriteString(project.Value() + "\n")
+// OpenProject opens the session of a project picked with ProjectPicker.
+func (sh *SessionHandler) OpenProject(ctx context.Context, rawPath string) error {
+	return sh.OpenSession(ctx, sessionNameOf(sh.config, rawPath), rawPath)
+}
+
+// Act does action with the entry at rawPath picked in ProjectPicker.
+// Renaming needs a name and updates the config file, so ActionRename is left
+// to AskSessionName and RenameSession.
+func (sh *SessionHandler) Act(ctx context.Context, action PickerAction, rawPath string) error {
+	switch action {
+	case ActionOpen:
+		return sh.OpenProject(ctx, rawPath)
+	case ActionKill:
+		return sh.KillSession(ctx, rawPath)
+	case ActionWindow:
+		return sh.OpenWindow(ctx, sh.dirOf(rawPath))
+	case ActionEdit:
+		return sh.edit(ctx, sh.dirOf(rawPath))
+	default:
+		return fmt.Errorf("%q:%w", action, ErrUnsupportedAction)
 	}
+}
 
-	if err := fzfCmd.Run(); err != nil {
-		return fmt.Errorf("failed to grab project path with fzf: %w", err)
+// dirOf returns the directory of the entry at rawPath, which is rawPath itself
+// except for a workspace session.
+func (sh *SessionHandler) dirOf(rawPath string) string {
+	if running, err := sh.manager.GetSession(rawPath); err == nil {
+		return running.ProjectPath.Value()
 	}
+	return rawPath
+}
 
-	rawPath := fzfCmd.OutBuf().String()
+// OpenWindow opens rawPath as a window of the current session, for a quick
+// look without leaving it. A window already in rawPath is selected instead.
+func (sh *SessionHandler) OpenWindow(ctx context.Context, rawPath string) error {
+	if !sh.tmux.IsInSession() {
+		return ErrNotInSession
+	}
+	// tmux reports where panes are with symlinks resolved.
+	resolved, err := filepath.EvalSymlinks(rawPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s as a window:%w", rawPath, err)
+	}
+	id, found, err := sh.tmux.FindCurrentWindow(ctx, resolved)
+	if err != nil {
+		return err
+	}
+	if found {
+		return sh.tmux.SelectWindow(ctx, id)
+	}
+
+	environ, err := sh.config.Environ(rawPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment of %s:%w", rawPath, err)
+	}
+	if err := sh.tmux.NewCurrentWindow(ctx, filepath.Base(rawPath), rawPath, environ); err != nil {
+		return fmt.Errorf("failed to open %s as a window:%w", rawPath, err)
+	}
+	return nil
+}
+
+func (sh *SessionHandler) edit(ctx context.Context, rawPath string) error {
+	editor := os.Getenv("EDITOR")
+	if len(strings.TrimSpace(editor)) == 0 {
+		return ErrNoEditor
+	}
+	if err := command.NewEditorCommand(ctx, editor, rawPath).Run(); err != nil {
+		return fmt.Errorf("failed to open %s in %s:%w", rawPath, editor, err)
+	}
+	return nil
+}
+
+// AskSessionName asks for a new name of the session of rawPath with fzf. The
+// name is empty when the prompt was cancelled or rawPath has no session.
+func (sh *SessionHandler) AskSessionName(ctx context.Context, rawPath string) (string, error) {
+	running, err := sh.manager.GetSession(rawPath)
+	// NOTE: a project has no session to rename yet; like killing it, this does nothing.
+	if errors.Is(err, session.ErrSessionNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	fzfCmd := command.NewFzfCommand(ctx, "--print-query", "--prompt", fmt.Sprintf("rename %s to> ", running.Name.Value()))
+	var exitErr *exec.ExitError
+	if err := fzfCmd.Run(); errors.As(err, &exitErr) && exitErr.ExitCode() == fzfInterrupted {
+		return "", nil
+	} else if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() != fzfNoMatch) {
+		return "", fmt.Errorf("failed to read session name with fzf:%w", err)
+	}
+	// With nothing to pick from, fzf prints the query alone.
+	return strings.TrimSpace(fzfCmd.OutBuf().String()), nil
+}
+
+// OpenSession attaches to the session of rawPath. When there is none yet,
+// it creates one named after rawName first.
+func (sh *SessionHandler) OpenSession(ctx context.Context, rawName string, rawPath string) error {
 	session, err := sh.manager.GetSession(rawPath)
 	// NOTE: if session is not found, create a new one.
 	if err != nil {
-		session := sh.manager.CreateSession(
-			rawPath,
-			rawPath,
-		)
-		if sh.tmux.IsInSession() {
-			return sh.tmux.SwitchToNewClient(ctx, session)
-		} else {
-			return sh.tmux.CreateAndAttach(ctx, session)
+		return sh.create(ctx, sh.manager.CreateSession(rawName, rawPath))
+	}
+
+	return sh.attach(ctx, session)
+}
+
+// NewScratchSession creates a throwaway session in a new temporary directory.
+// Without rawName, the session is named after the directory.
+func (sh *SessionHandler) NewScratchSession(ctx context.Context, rawName string) error {
+	if len(rawName) > 0 {
+		// tmux compares the name it is given, not the one typed.
+		name, err := sh.manager.SessionName(rawName)
+		if err != nil {
+			return err
+		}
+		if _, err := sh.manager.FindSession(name.Value()); err == nil {
+			return fmt.Errorf("%s:%w", name.Value(), session.ErrDuplicatedSession)
 		}
 	}
 
-	if sh.tmux.IsInSession() {
-		return sh.tmux.SwitchClient(ctx, session)
-	} else {
-		return sh.tmux.Attach(ctx, session)
+	dir, err := os.MkdirTemp("", scratchDirPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch directory:%w", err)
+	}
+	if len(rawName) == 0 {
+		rawName = "scratch-" + strings.TrimPrefix(filepath.Base(dir), scratchDirPrefix)
 	}
+
+	name, err := sh.manager.SessionName(rawName)
+	if err != nil {
+		return errors.Join(err, os.Remove(dir))
+	}
+	scratch := sh.manager.CreateScratchSession(name.Value(), dir)
+	if err := sh.createDetached(ctx, scratch); err != nil {
+		// NOTE: a failing post-create hook leaves the session behind, which must not outlive its directory.
+		_ = sh.tmux.Delete(ctx, scratch)
+		_ = sh.manager.DeleteSessions([]string{dir})
+		return errors.Join(err, os.RemoveAll(dir))
+	}
+	return sh.attach(ctx, scratch)
 }
 
-func (sh *SessionHandler) GrabExistingSession(ctx context.Context) error {
-	sessions := sh.manager.ListSessions()
-	fzfCmd := command.NewFzfCommand(ctx)
+// Prune kills the scratch sessions no client is attached to and removes
+// scratch directories left behind by sessions killed some other way, e.g.
+// with tmux kill-session or along with the tmux server. It returns the
+// directories it removed.
+func (sh *SessionHandler) Prune(ctx context.Context) ([]string, error) {
+	removed, killed := []string{}, []string{}
+	for _, scratch := range sortedSessions(sh.manager.ListSessions()) {
+		if !scratch.Scratch || scratch.Attached {
+			continue
+		}
+		if err := sh.kill(ctx, scratch); err != nil {
+			return removed, err
+		}
+		killed = append(killed, scratch.ProjectPath.Value())
+		if isScratchDir(scratch.ProjectPath.Value()) {
+			removed = append(removed, scratch.ProjectPath.Value())
+		}
+	}
+	if err := sh.manager.DeleteSessions(killed); err != nil {
+		return removed, err
+	}
 
-	for _, session := range sessions {
-		fzfCmd.InBuf().WriteString(session.ProjectPath.Value() + "\n")
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return removed, fmt.Errorf("failed to list scratch directories:%w", err)
+	}
+	for _, e := range entries {
+		dir := filepath.Join(os.TempDir(), e.Name())
+		if !e.IsDir() || !isScratchDir(dir) || slices.Contains(removed, dir) {
+			continue
+		}
+		if _, err := sh.manager.GetSession(dir); err == nil {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("failed to remove scratch directory %s:%w", dir, err)
+		}
+		removed = append(removed, dir)
 	}
+	return removed, nil
+}
 
-	if err := fzfCmd.Run(); err != nil {
+// OpenWorkspace opens every project of the workspace name. By default each
+// project gets its own session, named like any other project session, and the
+// first one is attached. With windows, a single session named after the
+// workspace holds one window per project instead.
+func (sh *SessionHandler) OpenWorkspace(ctx context.Context, name string, windows bool) error {
+	projects, exists := sh.config.Workspaces[name]
+	if !exists || len(projects) == 0 {
+		return fmt.Errorf("%s:%w", name, ErrNoSuchWorkspace)
+	}
+	for _, project := range projects {
+		if _, err := os.Stat(project.Value()); err != nil {
+			return fmt.Errorf("project %s of workspace %s is not available:%w", project.Value(), name, err)
+		}
+	}
+
+	if windows {
+		return sh.openWorkspaceWindows(ctx, name, projects)
+	}
+
+	for _, project := range projects[1:] {
+		if _, err := sh.manager.GetSession(project.Value()); err == nil {
+			continue
+		}
+		session := sh.manager.CreateSession(sessionNameOf(sh.config, project.Value()), project.Value())
+		if err := sh.createDetached(ctx, session); err != nil {
+			return fmt.Errorf("failed to create session for %s:%w", project.Value(), err)
+		}
+	}
+	return sh.OpenSession(ctx, sessionNameOf(sh.config, projects[0].Value()), projects[0].Value())
+}
+
+func (sh *SessionHandler) openWorkspaceWindows(ctx context.Context, name string, projects []types.String) error {
+	if _, err := sh.manager.SessionName(name); err != nil {
 		return err
 	}
+	if existing, err := sh.manager.GetSession(session.WorkspaceKey(name)); err == nil {
+		return sh.attach(ctx, existing)
+	}
 
-	grabbed := fzfCmd.OutBuf().String()
+	workspace := sh.manager.CreateWorkspaceSession(name, projects[0].Value())
+	if err := sh.createDetached(ctx, workspace); err != nil {
+		return fmt.Errorf("failed to create session for workspace %s:%w", name, err)
+	}
+	if err := sh.tmux.RenameWindow(ctx, workspace, filepath.Base(projects[0].Value())); err != nil {
+		return err
+	}
+	for _, project := range projects[1:] {
+		environ, err := sh.config.Environ(project.Value())
+		if err != nil {
+			return fmt.Errorf("failed to resolve environment of %s:%w", project.Value(), err)
+		}
+		if err := sh.tmux.NewWindow(ctx, workspace, filepath.Base(project.Value()), project.Value(), environ); err != nil {
+			return fmt.Errorf("failed to open %s in workspace %s:%w", project.Value(), name, err)
+		}
+	}
+	return sh.attach(ctx, workspace)
+}
 
-	session, err := sh.manager.GetSession(grabbed)
+// prepare resolves what session needs before tmux creates it.
+func (sh *SessionHandler) prepare(session *session.Session) error {
+	environ, err := sh.config.Environ(session.ProjectPath.Value())
 	if err != nil {
+		return fmt.Errorf("failed to resolve environment of %s:%w", session.ProjectPath.Value(), err)
+	}
+	session.Environ = environ
+
+	return nil
+}
+
+// createDetached creates session in the background, between the pre-create
+// and post-create hooks.
+func (sh *SessionHandler) createDetached(ctx context.Context, session *session.Session) error {
+	if err := sh.prepare(session); err != nil {
+		return err
+	}
+
+	if err := sh.hooks.Run(ctx, hook.PreCreate, session); err != nil {
+		return err
+	}
+	if err := sh.tmux.Create(ctx, session); err != nil {
+		return err
+	}
+	return sh.hooks.Run(ctx, hook.PostCreate, session)
+}
+
+func (sh *SessionHandler) create(ctx context.Context, session *session.Session) error {
+	if err := sh.createDetached(ctx, session); err != nil {
 		return err
 	}
 
+	return sh.attach(ctx, session)
+}
+
+func (sh *SessionHandler) attach(ctx context.Context, session *session.Session) error {
+	if err := sh.hooks.Run(ctx, hook.PreAttach, session); err != nil {
+		return err
+	}
 	if sh.tmux.IsInSession() {
-		return sh.tmux.SwitchClient(ctx, session)
+		if err := sh.tmux.SwitchClient(ctx, session); err != nil {
+			return err
+		}
+		return sh.hooks.Run(ctx, hook.PostAttach, session)
 	}
 
-	return sh.tmux.Attach(ctx, session)
+	// Outside tmux, attach holds the terminal until the client detaches, so
+	// post-attach hooks run alongside it rather than only then; a failing one
+	// is reported after detaching.
+	hooked := make(chan error, 1)
+	go func() {
+		hooked <- sh.hooks.Run(ctx, hook.PostAttach, session)
+	}()
+	err := sh.tmux.Attach(ctx, session)
+	return errors.Join(err, <-hooked)
 }
 
-func (sh *SessionHandler) DeleteSessions(ctx context.Context) error {
-	sessions := sh.manager.ListSessions()
-	fzfCmd := command.NewFzfCommand(ctx, "-m")
+// KillSession kills the session of rawPath if there is one.
+func (sh *SessionHandler) KillSession(ctx context.Context, rawPath string) error {
+	running, err := sh.manager.GetSession(rawPath)
+	if errors.Is(err, session.ErrSessionNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
 
-	for _, session := range sessions {
-		fzfCmd.InBuf().WriteString(session.ProjectPath.Value() + "\n")
+	if err := sh.kill(ctx, running); err != nil {
+		return err
 	}
+	return sh.manager.DeleteSessions([]string{rawPath})
+}
+
+// kill runs the pre-kill hooks, kills session in tmux and removes the directory of a scratch session.
+func (sh *SessionHandler) kill(ctx context.Context, session *session.Session) error {
+	if err := sh.hooks.Run(ctx, hook.PreKill, session); err != nil {
+		return err
+	}
+	if err := sh.tmux.Delete(ctx, session); err != nil {
+		return fmt.Errorf("failed to kill session %s:%w", session.Name.Value(), err)
+	}
+
+	if !session.Scratch {
+		return nil
+	}
+	// The tag is only a tmux option anyone could set, so never remove
+	// anything but a directory this tool created.
+	dir := session.ProjectPath.Value()
+	if !isScratchDir(dir) {
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove scratch directory %s:%w", dir, err)
+	}
+	return nil
+}
+
+// isScratchDir tells whether dir was created by NewScratchSession.
+func isScratchDir(dir string) bool {
+	return filepath.Dir(dir) == filepath.Clean(os.TempDir()) && strings.HasPrefix(filepath.Base(dir), scratchDirPrefix)
+}
+
+// sortedSessions sorts project sessions first and scratch sessions after them,
+// each by path.
+func sortedSessions(sessions []*session.Session) []*session.Session {
+	slices.SortFunc(sessions, func(a, b *session.Session) int {
+		if a.Scratch != b.Scratch {
+			if a.Scratch {
+				return 1
+			}
+			return -1
+		}
+		aKey, bKey := a.Key(), b.Key()
+		return strings.Compare(aKey.Value(), bKey.Value())
+	})
+	return sessions
+}
+
+// writeSessions lists the sessions in the order of sortedSessions. Scratch
+// sessions carry a label after a tab, which pickedPaths strips again.
+func (sh *SessionHandler) writeSessions(buf *bytes.Buffer) {
+	for _, session := range sortedSessions(sh.manager.ListSessions()) {
+		key := session.Key()
+		buf.WriteString(key.Value())
+		if session.Scratch {
+			buf.WriteString(scratchLabel)
+		}
+		buf.WriteString("\n")
+	}
+}
+
+// pickedPaths turns the lines fzf printed back into project paths.
+func pickedPaths(out string) []string {
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	for i, line := range lines {
+		lines[i], _, _ = strings.Cut(line, "\t")
+	}
+	return lines
+}
+
+// sessionNameOf names a new session after its project path, except for linked
+// git worktrees, which are named after their repository and branch.
+func sessionNameOf(config *iohelper.Config, rawPath string) string {
+	if worktree, exists := config.Worktrees[types.NewString(rawPath)]; exists {
+		return worktree.SessionName()
+	}
+
+	return rawPath
+}
+
+func (sh *SessionHandler) GrabExistingSession(ctx context.Context) error {
+	fzfCmd := command.NewFzfCommand(ctx)
+	sh.writeSessions(fzfCmd.InBuf())
+
+	if err := fzfCmd.Run(); err != nil {
+		return err
+	}
+
+	grabbed := pickedPaths(fzfCmd.OutBuf().String())[0]
+
+	session, err := sh.manager.GetSession(grabbed)
+	if err != nil {
+		return err
+	}
+
+	return sh.attach(ctx, session)
+}
+
+func (sh *SessionHandler) DeleteSessions(ctx context.Context) error {
+	fzfCmd := command.NewFzfCommand(ctx, "-m")
+	sh.writeSessions(fzfCmd.InBuf())
 
 	if err := fzfCmd.Run(); err != nil {
 		return err
 	}
 
-	deletes := fzfCmd.OutBuf().String()
-	// split all with "\n"
-	ds := strings.Split(strings.TrimSuffix(deletes, "\n"), "\n")
+	ds := pickedPaths(fzfCmd.OutBuf().String())
 	filtered := sh.manager.FilterSessions(ds)
 	if err := sh.manager.DeleteSessions(ds); err != nil {
 		return err
@@ -113,11 +494,40 @@ func (sh *SessionHandler) DeleteSessions(ctx context.Context) error {
 	eg := new(errgroup.Group)
 	for _, f := range filtered {
 		eg.Go(func() error {
-			if err := sh.tmux.Delete(ctx, f); err != nil {
-				return err
-			}
-			return nil
+			return sh.kill(ctx, f)
 		})
 	}
 	return eg.Wait()
 }
+
+// RenameSession renames the session identified by target, either its project
+// path or its session name. When target is empty, the session is picked with fzf.
+func (sh *SessionHandler) RenameSession(ctx context.Context, target string, rawName string) (*session.Session, error) {
+	name, err := sh.manager.SessionName(rawName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(target) == 0 {
+		fzfCmd := command.NewFzfCommand(ctx)
+		sh.writeSessions(fzfCmd.InBuf())
+
+		if err := fzfCmd.Run(); err != nil {
+			return nil, err
+		}
+		target = pickedPaths(fzfCmd.OutBuf().String())[0]
+	}
+
+	session, err := sh.manager.FindSession(target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rename in tmux first: the manager must not remember a name tmux rejected.
+	if err := sh.tmux.Rename(ctx, session, name); err != nil {
+		return nil, fmt.Errorf("failed to rename session %s to %s:%w", session.Name.Value(), name.Value(), err)
+	}
+
+	key := session.Key()
+	return sh.manager.RenameSession(key.Value(), name)
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/hook"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/tmux"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSessionHandler_writeSessions_ListsScratchSessionsLast(t *testing.T) {
+	t.Parallel()
+
+	sessions := map[types.String]*session.Session{
+		types.NewString("/tmp/tmux-sessionizer-scratch-1"): {
+			Name:        types.NewString("scratch-1"),
+			ProjectPath: types.NewString("/tmp/tmux-sessionizer-scratch-1"),
+			Scratch:     true,
+		},
+		types.NewString("/src/b"): {Name: types.NewString("/src/b"), ProjectPath: types.NewString("/src/b")},
+		types.NewString("/src/a"): {Name: types.NewString("/src/a"), ProjectPath: types.NewString("/src/a")},
+	}
+	sh := &SessionHandler{manager: session.NewSessionManager(sessions, session.NewTransformer())}
+
+	var buf bytes.Buffer
+	sh.writeSessions(&buf)
+
+	want := "/src/a\n/src/b\n/tmp/tmux-sessionizer-scratch-1" + scratchLabel + "\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("writeSessions() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPickedPaths_StripsLabels(t *testing.T) {
+	t.Parallel()
+
+	got := pickedPaths("/src/a\n/tmp/tmux-sessionizer-scratch-1" + scratchLabel + "\n")
+
+	want := []string{"/src/a", "/tmp/tmux-sessionizer-scratch-1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("pickedPaths() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSessionHandler_OpenWorkspace_RejectsUnknownWorkspace(t *testing.T) {
+	t.Parallel()
+
+	sh := &SessionHandler{
+		config:  &io.Config{Workspaces: map[string][]types.String{}},
+		manager: session.NewSessionManager(make(map[types.String]*session.Session), session.NewTransformer()),
+	}
+
+	if err := sh.OpenWorkspace(t.Context(), "micro", false); !errors.Is(err, ErrNoSuchWorkspace) {
+		t.Errorf("expected ErrNoSuchWorkspace, got %v", err)
+	}
+}
+
+func TestSessionHandler_NewScratchSession_RejectsTransformedDuplicate(t *testing.T) {
+	t.Parallel()
+
+	sessions := map[types.String]*session.Session{
+		types.NewString("/src/my_app"): {Name: types.NewString("my_app"), ProjectPath: types.NewString("/src/my_app")},
+	}
+	dots := session.NewTransformRule(
+		func(s string) string { return strings.ReplaceAll(s, ".", "_") },
+		func(s string) string { return s },
+	)
+	sh := &SessionHandler{manager: session.NewSessionManager(sessions, session.NewTransformer().WithRule(dots))}
+
+	if err := sh.NewScratchSession(t.Context(), "my.app"); !errors.Is(err, session.ErrDuplicatedSession) {
+		t.Errorf("expected ErrDuplicatedSession, got %v", err)
+	}
+}
+
+func TestSessionHandler_AskSessionName_IgnoresProject(t *testing.T) {
+	t.Parallel()
+
+	sh := &SessionHandler{manager: session.NewSessionManager(make(map[types.String]*session.Session), session.NewTransformer())}
+
+	// fzf is never started for a project, or the test would hang on it.
+	name, err := sh.AskSessionName(t.Context(), "/src/app")
+	if err != nil || len(name) > 0 {
+		t.Errorf("AskSessionName() = %q, %v, want no name and no error", name, err)
+	}
+}
+
+// TMPDIR is set, so the test must not run in parallel.
+func TestSessionHandler_Prune_RemovesScratchDirectoriesWithoutSession(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	attached, orphan, other := filepath.Join(tmp, scratchDirPrefix+"1"), filepath.Join(tmp, scratchDirPrefix+"2"), filepath.Join(tmp, "other")
+	for _, dir := range []string{attached, orphan, other} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sessions := map[types.String]*session.Session{
+		types.NewString(attached): {Name: types.NewString("scratch-1"), ProjectPath: types.NewString(attached), Scratch: true, Attached: true},
+	}
+	sh := &SessionHandler{manager: session.NewSessionManager(sessions, session.NewTransformer())}
+
+	removed, err := sh.Prune(t.Context())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if diff := cmp.Diff([]string{orphan}, removed); diff != "" {
+		t.Errorf("Prune() mismatch (-want +got):\n%s", diff)
+	}
+	for _, dir := range []string{attached, other} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("expected %s to be kept, got %v", dir, err)
+		}
+	}
+}
+
+//nolint:paralleltest // t.Setenv is incompatible with t.Parallel.
+func TestSessionHandler_OpenSession_RunsPostAttachHooksWhileAttached(t *testing.T) {
+	// A fake tmux stays attached until the post-attach hook has run, giving up
+	// after a while like a user who detaches without it.
+	bin, dir := t.TempDir(), t.TempDir()
+	marker, record := filepath.Join(dir, "hooked"), filepath.Join(dir, "attached")
+	script := "#!/bin/sh\n[ \"$1\" = attach ] || exit 0\n" +
+		"for i in $(seq 50); do [ -e \"$MARKER\" ] && echo hooked > \"$RECORD\" && exit 0; sleep 0.1; done\n"
+	if err := os.WriteFile(filepath.Join(bin, "tmux"), []byte(script), 0o755); err != nil { //nolint:gosec // the fake tmux must be executable.
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TMUX", "")
+	t.Setenv("MARKER", marker)
+	t.Setenv("RECORD", record)
+
+	running := session.NewSession(types.NewString("app"), types.NewString(dir))
+	sh := NewSessionHandler(
+		&io.Config{Hooks: map[hook.Event][]string{hook.PostAttach: {`touch "$MARKER"`}}},
+		session.NewSessionManager(map[types.String]*session.Session{running.Key(): running}, session.NewTransformer()),
+		tmux.NewTmux(),
+	)
+
+	if err := sh.OpenSession(t.Context(), "app", dir); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := os.Stat(record); err != nil {
+		t.Errorf("expected the post-attach hook to run while attached, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+var (
+	ErrNotRepository = errors.New("not the main worktree of a git repository")
+	ErrDirtyWorktree = errors.New("worktree has uncommitted changes")
+	ErrNotWorktree   = errors.New("not a worktree of the repository")
+)
+
+type WorktreeHandler struct {
+	config   *iohelper.Config
+	git      *git.Git
+	projects *ProjectHandler
+	sessions ISessionHandler
+}
+
+func NewWorktreeHandler(config *iohelper.Config, projects *ProjectHandler, sessions ISessionHandler) *WorktreeHandler {
+	return &WorktreeHandler{
+		config:   config,
+		git:      git.NewGit(),
+		projects: projects,
+		sessions: sessions,
+	}
+}
+
+// Dir is the directory the worktrees of repoAbs are created in: a directory
+// named after the repository under the configured worktree root, or a
+// <repository>.worktrees sibling of the repository when none is configured.
+func (wh *WorktreeHandler) Dir(repoAbs string) string {
+	if root := wh.config.WorktreeRoot.Value(); len(root) > 0 {
+		return filepath.Join(root, filepath.Base(repoAbs))
+	}
+	return repoAbs + ".worktrees"
+}
+
+// Path is where the worktree of branch lives. Slashes in branch names are
+// flattened so every worktree is an immediate child of Dir.
+func (wh *WorktreeHandler) Path(repoAbs string, branch string) string {
+	return filepath.Join(wh.Dir(repoAbs), strings.ReplaceAll(branch, "/", "-"))
+}
+
+// Create checks branch out in a new worktree of repoAbs, registers the
+// worktree directory as a project and opens a session in the worktree.
+// An existing worktree is opened as is.
+func (wh *WorktreeHandler) Create(ctx context.Context, repoAbs string, branch string) error {
+	if !wh.git.IsMainWorktree(repoAbs) {
+		return fmt.Errorf("%s:%w", repoAbs, ErrNotRepository)
+	}
+
+	path := wh.Path(repoAbs, branch)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), dirPermission); err != nil {
+			return fmt.Errorf("failed to create worktree directory:%w", err)
+		}
+		if err := wh.git.AddWorktree(ctx, repoAbs, path, branch); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to get status of worktree:%w", err)
+	}
+	worktree, err := wh.find(ctx, repoAbs, path)
+	if err != nil {
+		return err
+	}
+
+	// The worktrees of a listed repository are listed right below it; only
+	// the worktree of a repository outside every root needs to be registered.
+	listed := slices.ContainsFunc(wh.config.Projects, func(p types.String) bool { return p.Value() == repoAbs })
+	if !listed {
+		if err := wh.projects.RegisterProjectOnce(ctx, wh.config, path); err != nil {
+			return err
+		}
+	}
+
+	// Known as a worktree, the session is set up like one of the repository,
+	// with its env= entries and .env file.
+	worktree.Path = types.NewString(path)
+	wh.config.Worktrees[worktree.Path] = worktree
+	return wh.sessions.OpenSession(ctx, worktree.SessionName(), path)
+}
+
+// find returns the linked worktree of repoAbs at path, so whatever else is
+// in the way there is never registered as one.
+func (wh *WorktreeHandler) find(ctx context.Context, repoAbs string, path string) (git.Worktree, error) {
+	worktrees, err := wh.git.ListWorktrees(ctx, repoAbs)
+	if err != nil {
+		return git.Worktree{}, err
+	}
+	// git reports worktree paths with symlinks resolved.
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return git.Worktree{}, fmt.Errorf("failed to resolve worktree path:%w", err)
+	}
+	for _, w := range worktrees {
+		if w.Path.Value() == resolved {
+			return w, nil
+		}
+	}
+	return git.Worktree{}, fmt.Errorf("%s is in the way of the worktree of %s:%w", path, repoAbs, ErrNotWorktree)
+}
+
+// Remove kills the session of the worktree of branch and removes the
+// worktree. A worktree with uncommitted changes is left alone.
+func (wh *WorktreeHandler) Remove(ctx context.Context, repoAbs string, branch string) error {
+	path := wh.Path(repoAbs, branch)
+
+	clean, err := wh.git.IsClean(ctx, path)
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("%s:%w", path, ErrDirtyWorktree)
+	}
+
+	if err := wh.sessions.KillSession(ctx, path); err != nil {
+		return err
+	}
+	return wh.git.RemoveWorktree(ctx, repoAbs, path)
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	testutils "github.com/TlexCypher/my-tmux-sessionizer/test_utils"
+)
+
+// fakeSessionHandler records what would have been sent to tmux.
+type fakeSessionHandler struct {
+	ISessionHandler
+
+	opened map[string]string
+	killed []string
+}
+
+func newFakeSessionHandler() *fakeSessionHandler {
+	return &fakeSessionHandler{opened: make(map[string]string)}
+}
+
+func (f *fakeSessionHandler) OpenSession(_ context.Context, rawName string, rawPath string) error {
+	f.opened[rawPath] = rawName
+	return nil
+}
+
+func (f *fakeSessionHandler) KillSession(_ context.Context, rawPath string) error {
+	f.killed = append(f.killed, rawPath)
+	return nil
+}
+
+func TestWorktreeHandler_Path(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		worktreeRoot string
+		want         string
+	}{
+		{
+			name:         "next to the repository by default",
+			worktreeRoot: "",
+			want:         "/src/app.worktrees/feature-login",
+		},
+		{
+			name:         "under the configured worktree root",
+			worktreeRoot: "/worktrees",
+			want:         "/worktrees/app/feature-login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &io.Config{WorktreeRoot: types.NewString(tt.worktreeRoot)}
+			wh := NewWorktreeHandler(config, nil, nil)
+
+			if got := wh.Path("/src/app", "feature/login"); got != tt.want {
+				t.Errorf("Path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorktreeHandler_Create_RegistersAndOpensWorktree(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join(t.TempDir(), "app")
+	testutils.InitGitRepo(t, repo)
+	configFileAbs := writeConfig(t, io.ConfigPrefix)
+	sessions := newFakeSessionHandler()
+	config := &io.Config{Worktrees: make(map[types.String]git.Worktree)}
+	wh := NewWorktreeHandler(config, NewProjectHandler(configFileAbs), sessions)
+
+	if err := wh.Create(t.Context(), repo, "feature"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	path := repo + ".worktrees/feature"
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		t.Errorf("expected worktree at %s, got %v", path, err)
+	}
+	if want := io.ConfigPrefix + "\n" + io.ProjectPrefix + path; readConfig(t, configFileAbs) != want {
+		t.Errorf("expected config %q, got %q", want, readConfig(t, configFileAbs))
+	}
+	if got, want := sessions.opened[path], filepath.Base(filepath.Dir(repo))+"/"+filepath.Base(repo)+"@feature"; got != want {
+		t.Errorf("expected session %q to be opened, got %q", want, got)
+	}
+}
+
+func TestWorktreeHandler_Create_LeavesWorktreeOfListedRepositoryUnregistered(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	repo := filepath.Join(root, "app")
+	testutils.InitGitRepo(t, repo)
+	configFileAbs := writeConfig(t, io.ConfigPrefix+root)
+	config := &io.Config{
+		Registered: []types.String{types.NewString(root)},
+		Projects:   []types.String{types.NewString(repo)},
+		Worktrees:  make(map[types.String]git.Worktree),
+	}
+	wh := NewWorktreeHandler(config, NewProjectHandler(configFileAbs), newFakeSessionHandler())
+
+	if err := wh.Create(t.Context(), repo, "feature"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, want := readConfig(t, configFileAbs), io.ConfigPrefix+root; got != want {
+		t.Errorf("expected config to stay %q, got %q", want, got)
+	}
+}
+
+func TestWorktreeHandler_Create_RejectsDirectoryInTheWay(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join(t.TempDir(), "app")
+	testutils.InitGitRepo(t, repo)
+	configFileAbs := writeConfig(t, io.ConfigPrefix)
+	sessions := newFakeSessionHandler()
+	wh := NewWorktreeHandler(&io.Config{}, NewProjectHandler(configFileAbs), sessions)
+	if err := os.MkdirAll(wh.Path(repo, "feature"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wh.Create(t.Context(), repo, "feature"); !errors.Is(err, ErrNotWorktree) {
+		t.Errorf("expected ErrNotWorktree, got %v", err)
+	}
+	if got := readConfig(t, configFileAbs); got != io.ConfigPrefix {
+		t.Errorf("expected config to stay %q, got %q", io.ConfigPrefix, got)
+	}
+	if len(sessions.opened) != 0 {
+		t.Errorf("expected no session to be opened, got %v", sessions.opened)
+	}
+}
+
+func TestWorktreeHandler_Remove_KeepsDirtyWorktree(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join(t.TempDir(), "app")
+	testutils.InitGitRepo(t, repo)
+	sessions := newFakeSessionHandler()
+	wh := NewWorktreeHandler(&io.Config{}, nil, sessions)
+	path := wh.Path(repo, "feature")
+	testutils.Git(t, repo, "worktree", "add", "-q", "-b", "feature", path)
+	if err := os.WriteFile(filepath.Join(path, "wip.txt"), []byte("wip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wh.Remove(t.Context(), repo, "feature"); !errors.Is(err, ErrDirtyWorktree) {
+		t.Errorf("expected ErrDirtyWorktree, got %v", err)
+	}
+	if len(sessions.killed) != 0 {
+		t.Errorf("expected no session to be killed, got %v", sessions.killed)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected worktree to be kept, got %v", err)
+	}
+}
+
+func TestWorktreeHandler_Remove_KillsSessionAndRemovesCleanWorktree(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join(t.TempDir(), "app")
+	testutils.InitGitRepo(t, repo)
+	sessions := newFakeSessionHandler()
+	wh := NewWorktreeHandler(&io.Config{}, nil, sessions)
+	path := wh.Path(repo, "feature")
+	testutils.Git(t, repo, "worktree", "add", "-q", "-b", "feature", path)
+
+	if err := wh.Remove(t.Context(), repo, "feature"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions.killed) != 1 || sessions.killed[0] != path {
+		t.Errorf("expected session of %s to be killed, got %v", path, sessions.killed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected worktree to be removed, got %v", err)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 var (
@@ -31,6 +34,24 @@ func NewFzfCommand(ctx context.Context, opts ...string) *FzfCommand {
 	}
 }
 
+// NewFzfStreamCommand feeds fzf through a pipe instead of InBuf, so the
+// candidates written to the returned writer show up while fzf is running.
+// Start fzf before writing and close the writer once every candidate is in.
+func NewFzfStreamCommand(ctx context.Context, opts ...string) (*FzfCommand, io.WriteCloser, error) {
+	cmd := exec.CommandContext(ctx, "fzf", opts...)
+	outBuf := &bytes.Buffer{}
+	cmd.Stdout = outBuf
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &FzfCommand{
+		Cmd:    cmd,
+		outBuf: outBuf,
+	}, in, nil
+}
+
 func (fc *FzfCommand) Run() error {
 	err := fc.Cmd.Run()
 	if err != nil {
@@ -97,3 +118,79 @@ func (tc *TmuxCommand) Out() []byte {
 
 	return buf.Bytes()
 }
+
+type GitCommand struct {
+	*exec.Cmd
+
+	outBuf *bytes.Buffer
+	errBuf *bytes.Buffer
+}
+
+func NewGitCommand(ctx context.Context, args ...string) *GitCommand {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	outBuf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	// git is never interactive here, so stderr is kept to explain failures.
+	cmd.Stdout, cmd.Stderr = outBuf, errBuf
+
+	return &GitCommand{
+		Cmd:    cmd,
+		outBuf: outBuf,
+		errBuf: errBuf,
+	}
+}
+
+func (gc *GitCommand) Run() error {
+	err := gc.Cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(gc.errBuf.String()); len(msg) > 0 {
+			return fmt.Errorf("%s:%w", msg, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (gc *GitCommand) OutBuf() *bytes.Buffer {
+	return gc.outBuf
+}
+
+// NewShellCommand runs script with sh in dir. Its output goes to stderr, so
+// it never mixes with what tmux-sessionizer prints on stdout.
+func NewShellCommand(ctx context.Context, script string, dir string, env []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", script)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	killProcessGroup(cmd)
+
+	return cmd
+}
+
+// NewEditorCommand opens path in editor, a command line such as "code -w",
+// on the terminal tmux-sessionizer runs in.
+func NewEditorCommand(ctx context.Context, editor string, path string) *exec.Cmd {
+	// path is passed as an argument, so it is never parsed by the shell.
+	cmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Dir = path
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	return cmd
+}
+
+// Output runs a non-interactive command and returns its trimmed stdout.
+// Nothing is printed; stderr only explains a failure.
+func Output(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	outBuf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = outBuf, errBuf
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errBuf.String()); len(msg) > 0 {
+			return "", fmt.Errorf("%s:%w", msg, err)
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(outBuf.String()), nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package command
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup makes cancelling cmd kill everything the script started,
+// not only the shell, so a timed out hook leaves nothing behind.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
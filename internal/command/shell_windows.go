@@ -0,0 +1,8 @@
+//go:build windows
+
+package command
+
+import "os/exec"
+
+// killProcessGroup is a no-op: Windows has no process groups to signal.
+func killProcessGroup(_ *exec.Cmd) {}
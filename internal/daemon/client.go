@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+const (
+	// queryTimeout bounds how long a run waits for the daemon before it walks
+	// the roots itself; the daemon normally answers within milliseconds.
+	queryTimeout = time.Second
+)
+
+// request asks for the projects of Config, the absolute config file path.
+type request struct {
+	Config string `json:"config"`
+}
+
+type response struct {
+	Error     string     `json:"error,omitempty"`
+	Projects  []string   `json:"projects"`
+	Worktrees []worktree `json:"worktrees"`
+	SlowRoots []string   `json:"slow_roots"`
+}
+
+type worktree struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Branch     string `json:"branch"`
+}
+
+func newResponse(index *iohelper.ProjectIndex) response {
+	resp := response{
+		Projects:  stringValues(index.Projects),
+		Worktrees: make([]worktree, 0, len(index.Worktrees)),
+		SlowRoots: stringValues(index.SlowRoots),
+	}
+	for _, w := range index.Worktrees {
+		resp.Worktrees = append(resp.Worktrees, worktree{
+			Repository: w.Repository.Value(),
+			Path:       w.Path.Value(),
+			Branch:     w.Branch.Value(),
+		})
+	}
+	return resp
+}
+
+func (r response) index() *iohelper.ProjectIndex {
+	index := &iohelper.ProjectIndex{
+		Projects:  typedStrings(r.Projects),
+		Worktrees: make(map[types.String]git.Worktree, len(r.Worktrees)),
+		SlowRoots: typedStrings(r.SlowRoots),
+	}
+	for _, w := range r.Worktrees {
+		index.Worktrees[types.NewString(w.Path)] = git.Worktree{
+			Repository: types.NewString(w.Repository),
+			Path:       types.NewString(w.Path),
+			Branch:     types.NewString(w.Branch),
+		}
+	}
+	return index
+}
+
+// Query asks the daemon listening on socketPath for the projects of
+// configFileAbs. Any error means the caller has to walk the roots itself.
+func Query(ctx context.Context, socketPath string, configFileAbs string) (*iohelper.ProjectIndex, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := checkSocketDir(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("daemon is not running:%w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(request{Config: configFileAbs}); err != nil {
+		return nil, fmt.Errorf("failed to ask daemon:%w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read daemon answer:%w", err)
+	}
+	if len(resp.Error) > 0 {
+		return nil, fmt.Errorf("%s:%w", resp.Error, ErrNoAnswer)
+	}
+	return resp.index(), nil
+}
+
+func stringValues(ss []types.String) []string {
+	values := make([]string, 0, len(ss))
+	for _, s := range ss {
+		values = append(values, s.Value())
+	}
+	return values
+}
+
+func typedStrings(values []string) []types.String {
+	ss := make([]types.String, 0, len(values))
+	for _, v := range values {
+		ss = append(ss, types.NewString(v))
+	}
+	return ss
+}
@@ -0,0 +1,258 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("daemon is already running")
+	ErrNoAnswer       = errors.New("daemon could not answer")
+	// ErrUnsafeSocketDir means the socket directory is open to other users.
+	ErrUnsafeSocketDir = errors.New("socket directory is not private")
+)
+
+const (
+	// debounce coalesces the burst of events a checkout or a build causes
+	// into a single reload.
+	debounce = 300 * time.Millisecond
+	// resync reloads now and then anyway, for what cannot be watched: linked
+	// worktrees, roots that did not exist yet and network mounts.
+	resync = 5 * time.Minute
+	// connTimeout bounds a single request, so a stuck client holds nothing.
+	connTimeout = 5 * time.Second
+)
+
+// SocketPath is where the daemon listens: $XDG_RUNTIME_DIR/tmux-sessionizer,
+// or a directory of the user's own in os.TempDir when $XDG_RUNTIME_DIR is
+// unset or relative.
+func SocketPath(getenv func(string) string) string {
+	if runtimeDir := getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(runtimeDir) {
+		return filepath.Join(runtimeDir, "tmux-sessionizer", "daemon.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("tmux-sessionizer-%d", os.Getuid()), "daemon.sock")
+}
+
+// Listen listens on socketPath, unless another daemon already does.
+func Listen(socketPath string) (net.Listener, error) {
+	// Only the user may talk to the daemon.
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory:%w", err)
+	}
+	// MkdirAll keeps a directory that already exists as it is.
+	if err := checkSocketDir(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s:%w", socketPath, ErrAlreadyRunning)
+	}
+	// A socket left behind by a daemon that died answers nothing.
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket:%w", err)
+	}
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s:%w", socketPath, err)
+	}
+	return l, nil
+}
+
+// Daemon keeps the projects of a config file in memory, walking the roots
+// again whenever a directory listed to find them changes, and serves them
+// over a Unix socket.
+type Daemon struct {
+	configFileAbs string
+	load          func(ctx context.Context) (*iohelper.Config, error)
+	log           io.Writer
+	watcher       *fsnotify.Watcher
+
+	// reloadMu serializes reloads, which run for requests as well as for events.
+	reloadMu sync.Mutex
+	mu       sync.Mutex
+	index    *iohelper.ProjectIndex
+	// files holds the modification time of every config file read, to tell
+	// a stale index when the config file changed.
+	files   map[string]time.Time
+	watched map[string]struct{}
+}
+
+// New makes a daemon serving configFileAbs, whose config is read with load.
+func New(configFileAbs string, load func(ctx context.Context) (*iohelper.Config, error), log io.Writer) *Daemon {
+	return &Daemon{
+		configFileAbs: configFileAbs,
+		load:          load,
+		log:           log,
+		files:         make(map[string]time.Time),
+		watched:       make(map[string]struct{}),
+	}
+}
+
+// Run serves on l until ctx is done. The config must be readable at start;
+// a later failure to read it only keeps the previous projects.
+func (d *Daemon) Run(ctx context.Context, l net.Listener) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch roots:%w", err)
+	}
+	defer watcher.Close()
+	d.watcher = watcher
+
+	if err := d.reload(ctx); err != nil {
+		return err
+	}
+
+	defer l.Close()
+	go d.accept(ctx, l)
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	ticker := time.NewTicker(resync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// Writes and attribute changes never change what a directory lists.
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				timer.Reset(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(d.log, "watch error: %v\n", err)
+		case <-timer.C:
+			d.reloadLogged(ctx)
+		case <-ticker.C:
+			d.reloadLogged(ctx)
+		}
+	}
+}
+
+func (d *Daemon) accept(ctx context.Context, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			// The listener is closed when Run returns.
+			return
+		}
+		go d.serve(ctx, conn)
+	}
+}
+
+func (d *Daemon) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(connTimeout))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	_ = json.NewEncoder(conn).Encode(d.answer(ctx, req.Config))
+}
+
+func (d *Daemon) answer(ctx context.Context, configFileAbs string) response {
+	if configFileAbs != d.configFileAbs {
+		return response{Error: fmt.Sprintf("serving %s, not %s", d.configFileAbs, configFileAbs)}
+	}
+	// An event may still be pending, but an edited config must never be
+	// answered with the roots it had before.
+	if d.stale() {
+		if err := d.reload(ctx); err != nil {
+			return response{Error: err.Error()}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return newResponse(d.index)
+}
+
+func (d *Daemon) stale() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for file, mtime := range d.files {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(mtime) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Daemon) reloadLogged(ctx context.Context) {
+	if err := d.reload(ctx); err != nil {
+		fmt.Fprintf(d.log, "failed to reload, keeping the previous projects: %v\n", err)
+	}
+}
+
+func (d *Daemon) reload(ctx context.Context) error {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	config, err := d.load(ctx)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]time.Time, len(config.Files))
+	for _, file := range config.Files {
+		if info, err := os.Stat(file); err == nil {
+			files[file] = info.ModTime()
+		}
+	}
+
+	d.mu.Lock()
+	d.index = iohelper.IndexOf(config)
+	d.files = files
+	d.mu.Unlock()
+
+	d.watch(config.ListedDirs)
+	fmt.Fprintf(d.log, "indexed %d project(s), watching %d director(ies)\n", len(config.Projects), len(d.watched))
+	return nil
+}
+
+// watch makes the watched directories exactly dirs.
+func (d *Daemon) watch(dirs []types.String) {
+	wanted := make(map[string]struct{}, len(dirs))
+	for _, dir := range dirs {
+		wanted[dir.Value()] = struct{}{}
+	}
+
+	for dir := range d.watched {
+		if _, exists := wanted[dir]; !exists {
+			// NOTE: a removed directory is no longer watched anyway.
+			_ = d.watcher.Remove(dir)
+			delete(d.watched, dir)
+		}
+	}
+	for dir := range wanted {
+		if _, exists := d.watched[dir]; exists {
+			continue
+		}
+		if err := d.watcher.Add(dir); err != nil {
+			// e.g. the inotify watch limit; the resync still catches its changes.
+			fmt.Fprintf(d.log, "failed to watch %s: %v\n", dir, err)
+			continue
+		}
+		d.watched[dir] = struct{}{}
+	}
+}
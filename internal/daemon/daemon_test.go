@@ -0,0 +1,234 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	iohelper "github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSocketPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "XDG_RUNTIME_DIR",
+			env:  map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"},
+			want: "/run/user/1000/tmux-sessionizer/daemon.sock",
+		},
+		{
+			name: "relative XDG_RUNTIME_DIR is ignored",
+			env:  map[string]string{"XDG_RUNTIME_DIR": "run"},
+			want: filepath.Join(os.TempDir(), "tmux-sessionizer-"+strconv.Itoa(os.Getuid()), "daemon.sock"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := SocketPath(func(key string) string { return tt.env[key] }); got != tt.want {
+				t.Errorf("SocketPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// startDaemon runs a daemon serving the projects of a config file listing
+// root with depth 1, and returns its socket.
+func startDaemon(t *testing.T, root string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	configFileAbs := filepath.Join(dir, "config")
+	if err := os.WriteFile(configFileAbs, []byte("default="+root+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Listen creates the socket directory private, a temporary one is not.
+	socketPath := filepath.Join(dir, "run", "d.sock")
+	l, err := Listen(socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	load := func(ctx context.Context) (*iohelper.Config, error) {
+		return iohelper.NewConfigParser().ReadConfig(ctx, iohelper.NewFiler(), configFileAbs)
+	}
+	done := make(chan error, 1)
+	go func() { done <- New(configFileAbs, load, io.Discard).Run(ctx, l) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	})
+	return socketPath, configFileAbs
+}
+
+func queryProjects(t *testing.T, socketPath string, configFileAbs string) ([]string, error) {
+	t.Helper()
+
+	index, err := Query(context.Background(), socketPath, configFileAbs)
+	if err != nil {
+		return nil, err
+	}
+	return stringValues(index.Projects), nil
+}
+
+func TestDaemon_ServesProjects(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, d := range []string{"app", "tool"} {
+		if err := os.Mkdir(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	socketPath, configFileAbs := startDaemon(t, root)
+
+	// Run starts serving once the roots were walked, so the first query may
+	// come too early.
+	var got []string
+	var err error
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if got, err = queryProjects(t, socketPath, configFileAbs); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	want := []string{filepath.Join(root, "app"), filepath.Join(root, "tool")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Query() projects mismatch (-want +got):\n%s", diff)
+	}
+
+	// A new project shows up once the watcher saw it, without a restart.
+	if err := os.Mkdir(filepath.Join(root, "new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	newProject := filepath.Join(root, "new")
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if got, err = queryProjects(t, socketPath, configFileAbs); err == nil && slices.Contains(got, newProject) {
+			return
+		}
+	}
+	t.Errorf("Query() projects = %v, err = %v, want %s among them", got, err, newProject)
+}
+
+func TestDaemon_ReloadsEditedConfig(t *testing.T) {
+	t.Parallel()
+
+	root, other := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(other, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	socketPath, configFileAbs := startDaemon(t, root)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := queryProjects(t, socketPath, configFileAbs); err == nil {
+			break
+		}
+	}
+
+	// The mtime must change even on file systems with a coarse resolution.
+	if err := os.WriteFile(configFileAbs, []byte("default="+other+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(configFileAbs, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := queryProjects(t, socketPath, configFileAbs)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(other, "app")}, got); diff != "" {
+		t.Errorf("Query() projects mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDaemon_RejectsOtherConfig(t *testing.T) {
+	t.Parallel()
+
+	socketPath, configFileAbs := startDaemon(t, t.TempDir())
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := queryProjects(t, socketPath, configFileAbs); err == nil {
+			break
+		}
+	}
+
+	if _, err := Query(context.Background(), socketPath, "/other/config"); !errors.Is(err, ErrNoAnswer) {
+		t.Errorf("Query() error = %v, want %v", err, ErrNoAnswer)
+	}
+}
+
+func TestListen(t *testing.T) {
+	t.Parallel()
+
+	socketPath := filepath.Join(t.TempDir(), "run", "d.sock")
+	l, err := Listen(socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if _, err := Listen(socketPath); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Listen() error = %v, want %v", err, ErrAlreadyRunning)
+	}
+
+	// The socket file outlives a listener that is gone without cleaning up.
+	l.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+	l.Close()
+	l, err = Listen(socketPath)
+	if err != nil {
+		t.Fatalf("Listen() over a stale socket error = %v", err)
+	}
+	l.Close()
+
+	if _, err := Query(context.Background(), socketPath, "/config"); err == nil {
+		t.Error("Query() without a daemon error = nil, want an error")
+	}
+}
+
+func TestListen_RejectsSocketDirOfOthers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	open, link := filepath.Join(dir, "open"), filepath.Join(dir, "link")
+	if err := os.Mkdir(open, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// Mkdir is subject to the umask, Chmod is not.
+	if err := os.Chmod(open, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	private := filepath.Join(dir, "private")
+	if err := os.Mkdir(private, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, socketDir := range []string{open, link} {
+		socketPath := filepath.Join(socketDir, "d.sock")
+		if _, err := Listen(socketPath); !errors.Is(err, ErrUnsafeSocketDir) {
+			t.Errorf("Listen(%q) error = %v, want %v", socketPath, err, ErrUnsafeSocketDir)
+		}
+		if _, err := Query(context.Background(), socketPath, "/config"); !errors.Is(err, ErrUnsafeSocketDir) {
+			t.Errorf("Query(%q) error = %v, want %v", socketPath, err, ErrUnsafeSocketDir)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkSocketDir makes sure only the user can reach a socket in dir, like
+// tmux does for its own: dir must be a real directory of the user's own that
+// nobody else may enter. Otherwise another user could serve bogus projects or
+// listen in on the requests.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory:%w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory:%w", dir, ErrUnsafeSocketDir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d:%w", dir, stat.Uid, ErrUnsafeSocketDir)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		return fmt.Errorf("%s has mode %#o, want 0700:%w", dir, perm, ErrUnsafeSocketDir)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package daemon
+
+// checkSocketDir is a no-op: Windows has no owner and mode bits to check.
+func checkSocketDir(_ string) error {
+	return nil
+}
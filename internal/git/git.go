@@ -0,0 +1,191 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+var (
+	ErrInvalidRemote = errors.New("invalid remote")
+)
+
+const (
+	// shortHashLen is how much of HEAD names a detached worktree.
+	shortHashLen = 7
+)
+
+// Worktree is a working tree checked out from Repository, the path of the
+// main worktree. The main worktree itself has Path equal to Repository.
+type Worktree struct {
+	Repository types.String
+	Path       types.String
+	Branch     types.String
+}
+
+// SessionName derives a session name from the repository, with the directory
+// it lives in, and the branch, so every worktree of a repository gets a
+// session of its own. The directory keeps forks apart, e.g. a/app and b/app of
+// a ghq layout.
+func (w Worktree) SessionName() string {
+	repo := w.Repository.Value()
+	return filepath.Base(filepath.Dir(repo)) + "/" + filepath.Base(repo) + "@" + w.Branch.Value()
+}
+
+type Git struct{}
+
+func NewGit() *Git {
+	return &Git{}
+}
+
+// IsMainWorktree reports whether path is the main worktree of a repository.
+// Linked worktrees have a .git file instead of a .git directory.
+func (g *Git) IsMainWorktree(path string) bool {
+	fi, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil && fi.IsDir()
+}
+
+// IsLinkedWorktree reports whether path is a worktree linked to a repository
+// elsewhere, which has a .git file pointing into the worktrees directory
+// there. A submodule has a .git file too, pointing into the modules directory.
+func (g *Git) IsLinkedWorktree(path string) bool {
+	b, err := os.ReadFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return false
+	}
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(b)), "gitdir:")
+	return found && strings.Contains(filepath.ToSlash(gitDir), "/worktrees/")
+}
+
+// ListWorktrees returns the linked worktrees of the repository at repo,
+// without the main worktree and without worktrees whose directory is gone.
+func (g *Git) ListWorktrees(ctx context.Context, repo string) ([]Worktree, error) {
+	gitCmd := command.NewGitCommand(ctx, "-C", repo, "worktree", "list", "--porcelain")
+	if err := gitCmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to list worktrees of %s with `git worktree list --porcelain`:%w", repo, err)
+	}
+
+	return ParseWorktrees(types.NewString(repo), gitCmd.OutBuf().String()), nil
+}
+
+// ParseWorktrees parses the output of `git worktree list --porcelain`.
+// Records are separated by blank lines; the first record is the main worktree.
+func ParseWorktrees(repo types.String, porcelain string) []Worktree {
+	worktrees := make([]Worktree, 0)
+
+	for i, record := range strings.Split(strings.TrimSpace(porcelain), "\n\n") {
+		var (
+			path, branch, head string
+			skip               bool
+		)
+		for line := range strings.SplitSeq(record, "\n") {
+			key, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+			switch key {
+			case "worktree":
+				path = value
+			case "HEAD":
+				head = value
+			case "branch":
+				branch = strings.TrimPrefix(value, "refs/heads/")
+			case "bare", "prunable":
+				skip = true
+			}
+		}
+
+		if i == 0 || skip || len(path) == 0 {
+			continue
+		}
+		if len(branch) == 0 {
+			branch = head[:min(len(head), shortHashLen)]
+		}
+		worktrees = append(worktrees, Worktree{
+			Repository: repo,
+			Path:       types.NewString(path),
+			Branch:     types.NewString(branch),
+		})
+	}
+
+	return worktrees
+}
+
+// HasBranch reports whether the repository at repo has a local branch.
+func (g *Git) HasBranch(ctx context.Context, repo string, branch string) bool {
+	gitCmd := command.NewGitCommand(ctx, "-C", repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return gitCmd.Run() == nil
+}
+
+// AddWorktree checks branch out at path, creating the branch from HEAD when
+// the repository does not have it yet.
+func (g *Git) AddWorktree(ctx context.Context, repo string, path string, branch string) error {
+	args := []string{"-C", repo, "worktree", "add", path, branch}
+	if !g.HasBranch(ctx, repo, branch) {
+		args = []string{"-C", repo, "worktree", "add", "-b", branch, path}
+	}
+
+	gitCmd := command.NewGitCommand(ctx, args...)
+	if err := gitCmd.Run(); err != nil {
+		return fmt.Errorf("failed to add worktree %s for %s:%w", path, branch, err)
+	}
+	return nil
+}
+
+// IsClean reports whether the worktree at path has neither modified nor
+// untracked files.
+func (g *Git) IsClean(ctx context.Context, path string) (bool, error) {
+	gitCmd := command.NewGitCommand(ctx, "-C", path, "status", "--porcelain")
+	if err := gitCmd.Run(); err != nil {
+		return false, fmt.Errorf("failed to get status of %s:%w", path, err)
+	}
+	return len(strings.TrimSpace(gitCmd.OutBuf().String())) == 0, nil
+}
+
+func (g *Git) RemoveWorktree(ctx context.Context, repo string, path string) error {
+	gitCmd := command.NewGitCommand(ctx, "-C", repo, "worktree", "remove", path)
+	if err := gitCmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove worktree %s:%w", path, err)
+	}
+	return nil
+}
+
+func (g *Git) Clone(ctx context.Context, url string, dest string) error {
+	gitCmd := command.NewGitCommand(ctx, "clone", "--quiet", url, dest)
+	if err := gitCmd.Run(); err != nil {
+		return fmt.Errorf("failed to clone %s:%w", url, err)
+	}
+	return nil
+}
+
+// RemoteDir maps a remote to the ghq style host/owner/repository directory it
+// is cloned into. It understands URLs (https://, ssh://, git://, file://),
+// scp-like addresses (git@host:owner/repo) and local paths, which like file://
+// URLs are put under localhost.
+func RemoteDir(remote string) (string, error) {
+	host, path := "localhost", remote
+	if u, err := url.Parse(remote); err == nil && len(u.Scheme) > 1 {
+		if u.Scheme != "file" {
+			host = u.Hostname()
+		}
+		path = u.Path
+	} else if colon := strings.Index(remote, ":"); colon > 0 && !strings.Contains(remote[:colon], "/") {
+		// The user, if any, comes before the host; an @ after the colon is part of the path.
+		host, path = remote[strings.Index(remote[:colon], "@")+1:colon], remote[colon+1:]
+	}
+
+	segments := strings.FieldsFunc(strings.TrimSuffix(filepath.ToSlash(path), "/"), func(r rune) bool { return r == '/' })
+	if len(host) == 0 || len(segments) < 2 {
+		return "", fmt.Errorf("%s has no owner/repository to clone into:%w", remote, ErrInvalidRemote)
+	}
+
+	owner, repo := segments[len(segments)-2], strings.TrimSuffix(segments[len(segments)-1], ".git")
+	if len(repo) == 0 || repo == "." || repo == ".." || owner == "." || owner == ".." {
+		return "", fmt.Errorf("%s has no owner/repository to clone into:%w", remote, ErrInvalidRemote)
+	}
+	return filepath.Join(host, owner, repo), nil
+}
@@ -0,0 +1,175 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseWorktrees(t *testing.T) {
+	t.Parallel()
+
+	repo := types.NewString("/src/app")
+
+	tests := []struct {
+		name      string
+		porcelain string
+		want      []Worktree
+	}{
+		{
+			name:      "main worktree only",
+			porcelain: "worktree /src/app\nHEAD 1234567890abcdef\nbranch refs/heads/main\n",
+			want:      []Worktree{},
+		},
+		{
+			name: "linked worktrees on a branch and detached",
+			porcelain: "worktree /src/app\nHEAD 1234567890abcdef\nbranch refs/heads/main\n\n" +
+				"worktree /src/app.worktrees/feature\nHEAD abcdef1234567890\nbranch refs/heads/feature/login\n\n" +
+				"worktree /tmp/review\nHEAD fedcba0987654321\ndetached\n",
+			want: []Worktree{
+				{Repository: repo, Path: types.NewString("/src/app.worktrees/feature"), Branch: types.NewString("feature/login")},
+				{Repository: repo, Path: types.NewString("/tmp/review"), Branch: types.NewString("fedcba0")},
+			},
+		},
+		{
+			name: "prunable worktrees are skipped",
+			porcelain: "worktree /src/app\nHEAD 1234567890abcdef\nbranch refs/heads/main\n\n" +
+				"worktree /gone\nHEAD abcdef1234567890\nbranch refs/heads/old\nprunable gitdir file points to non-existent location\n",
+			want: []Worktree{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ParseWorktrees(repo, tt.porcelain)
+			if diff := cmp.Diff(tt.want, got, cmp.Comparer(func(a, b types.String) bool {
+				return a.Value() == b.Value()
+			})); diff != "" {
+				t.Errorf("ParseWorktrees() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWorktree_SessionName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		repo string
+		want string
+	}{
+		{
+			name: "repository",
+			repo: "/src/github.com/a/app",
+			want: "a/app@feature",
+		},
+		{
+			name: "fork with the same name",
+			repo: "/src/github.com/b/app",
+			want: "b/app@feature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := Worktree{
+				Repository: types.NewString(tt.repo),
+				Path:       types.NewString(tt.repo + ".worktrees/feature"),
+				Branch:     types.NewString("feature"),
+			}
+			if got := w.SessionName(); got != tt.want {
+				t.Errorf("SessionName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGit_IsLinkedWorktree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		dotGit string
+		want   bool
+	}{
+		{
+			name:   "linked worktree",
+			dotGit: "gitdir: /src/app/.git/worktrees/feature\n",
+			want:   true,
+		},
+		{
+			name:   "submodule",
+			dotGit: "gitdir: ../.git/modules/lib\n",
+			want:   false,
+		},
+		{
+			name: "main worktree",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			dotGit := filepath.Join(dir, ".git")
+			var err error
+			if len(tt.dotGit) > 0 {
+				err = os.WriteFile(dotGit, []byte(tt.dotGit), 0o600)
+			} else {
+				err = os.Mkdir(dotGit, 0o755)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := NewGit().IsLinkedWorktree(dir); got != tt.want {
+				t.Errorf("IsLinkedWorktree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteDir(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		remote  string
+		want    string
+		wantErr error
+	}{
+		{name: "https URL", remote: "https://github.com/owner/repo.git", want: "github.com/owner/repo"},
+		{name: "https URL without .git", remote: "https://gitlab.com/group/sub/repo", want: "gitlab.com/sub/repo"},
+		{name: "ssh URL with port", remote: "ssh://git@example.com:2222/owner/repo.git", want: "example.com/owner/repo"},
+		{name: "scp-like address", remote: "git@github.com:owner/repo.git", want: "github.com/owner/repo"},
+		{name: "scp-like address with @ in the path", remote: "1host:owner/re@po", want: "1host/owner/re@po"},
+		{name: "file URL", remote: "file:///srv/git/owner/repo.git", want: "localhost/owner/repo"},
+		{name: "bare repository path", remote: "/srv/git/owner/repo.git", want: "localhost/owner/repo"},
+		{name: "no owner", remote: "https://example.com/repo.git", wantErr: ErrInvalidRemote},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := RemoteDir(tt.remote)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoteDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("RemoteDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+)
+
+type Event string
+
+const (
+	PreCreate  Event = "pre-create"
+	PostCreate Event = "post-create"
+	PreAttach  Event = "pre-attach"
+	PostAttach Event = "post-attach"
+	PreKill    Event = "pre-kill"
+)
+
+const (
+	// DefaultTimeout bounds a hook unless the config file sets hook-timeout=.
+	DefaultTimeout = 30 * time.Second
+)
+
+const (
+	EnvEvent       = "TMUX_SESSIONIZER_EVENT"
+	EnvSessionName = "TMUX_SESSIONIZER_SESSION"
+	EnvProjectPath = "TMUX_SESSIONIZER_PATH"
+)
+
+var (
+	ErrUnknownEvent = errors.New("unknown hook event")
+)
+
+// ParseEvent accepts the event names written in the config file.
+func ParseEvent(raw string) (Event, error) {
+	switch e := Event(raw); e {
+	case PreCreate, PostCreate, PreAttach, PostAttach, PreKill:
+		return e, nil
+	default:
+		return "", fmt.Errorf("%q:%w", raw, ErrUnknownEvent)
+	}
+}
+
+type Runner struct {
+	hooks   map[Event][]string
+	timeout time.Duration
+}
+
+func NewRunner(hooks map[Event][]string, timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Runner{
+		hooks:   hooks,
+		timeout: timeout,
+	}
+}
+
+// Run runs the hooks of event one after another in the project directory.
+// The first failing hook stops the rest, so a failing pre- hook can veto.
+func (r *Runner) Run(ctx context.Context, event Event, session *session.Session) error {
+	for _, script := range r.hooks[event] {
+		if err := r.run(ctx, event, session, script); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, event Event, session *session.Session, script string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// Later entries win, so the session's own environment cannot shadow these.
+	env := append(slices.Clone(session.Environ),
+		EnvEvent+"="+string(event),
+		EnvSessionName+"="+session.Name.Value(),
+		EnvProjectPath+"="+session.ProjectPath.Value(),
+	)
+
+	cmd := command.NewShellCommand(ctx, script, session.ProjectPath.Value(), env)
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s hook `%s` timed out after %s:%w", event, script, r.timeout, ctx.Err())
+		}
+		return fmt.Errorf("%s hook `%s` failed:%w", event, script, err)
+	}
+
+	return nil
+}
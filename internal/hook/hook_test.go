@@ -0,0 +1,79 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+func newTestSession(t *testing.T) *session.Session {
+	t.Helper()
+
+	s := session.NewSession(types.NewString("api"), types.NewString(t.TempDir()))
+	s.Environ = []string{"AWS_PROFILE=api"}
+	return s
+}
+
+func TestRunner_Run_PassesSessionToHook(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession(t)
+	r := NewRunner(map[Event][]string{
+		PostCreate: {`printf '%s %s %s %s' "$TMUX_SESSIONIZER_EVENT" "$TMUX_SESSIONIZER_SESSION" "$AWS_PROFILE" "$PWD" > out`},
+	}, 0)
+
+	if err := r.Run(t.Context(), PostCreate, s); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(s.ProjectPath.Value(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PWD may resolve symlinks, so only compare the fixed part.
+	if want := "post-create api api "; string(got[:len(want)]) != want {
+		t.Errorf("hook saw %q, want prefix %q", got, want)
+	}
+}
+
+func TestRunner_Run_StopsAtFirstFailingHook(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession(t)
+	r := NewRunner(map[Event][]string{
+		PreKill: {"exit 3", "touch ran"},
+	}, 0)
+
+	if err := r.Run(t.Context(), PreKill, s); err == nil {
+		t.Error("expected error from failing hook, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(s.ProjectPath.Value(), "ran")); !os.IsNotExist(err) {
+		t.Errorf("expected second hook not to run, got %v", err)
+	}
+}
+
+func TestRunner_Run_TimesOut(t *testing.T) {
+	t.Parallel()
+
+	r := NewRunner(map[Event][]string{
+		PreCreate: {"sleep 5"},
+	}, 50*time.Millisecond)
+
+	if err := r.Run(t.Context(), PreCreate, newTestSession(t)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestParseEvent_RejectsUnknownEvent(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseEvent("post-kill"); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent, got %v", err)
+	}
+}
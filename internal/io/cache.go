@@ -0,0 +1,268 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+const (
+	// projectCacheVersion is bumped whenever the cache format changes; a cache
+	// of another version is dropped and rebuilt.
+	projectCacheVersion = 2
+	// racyWindow is how close to the walk a directory time may be and still be
+	// trusted. Some file systems keep times in whole seconds, so a directory
+	// changed right after it was listed may keep the time it was listed with.
+	racyWindow = 2 * time.Second
+)
+
+// CacheFile is where the projects found under each root are cached:
+// $XDG_CACHE_HOME/tmux-sessionizer/projects.json, or ~/.cache when
+// $XDG_CACHE_HOME is unset or relative.
+func CacheFile(filer *Filer, getenv func(string) string) (string, error) {
+	cacheHome := getenv("XDG_CACHE_HOME")
+	if !filepath.IsAbs(cacheHome) {
+		home, err := filer.ExpandTildeAsHomeDir("~/.cache")
+		if err != nil {
+			return "", err
+		}
+		cacheHome = home
+	}
+	return filepath.Join(cacheHome, "tmux-sessionizer", "projects.json"), nil
+}
+
+// ProjectCache remembers the projects found under each root, with the
+// modification time of every directory listed to find them. Creating,
+// removing or renaming a directory changes the time of its parent, so a root
+// is only walked again when one of those times changed. A nil cache caches
+// nothing.
+type ProjectCache struct {
+	path  string
+	mu    sync.Mutex
+	file  projectCacheFile
+	dirty bool
+	// seen holds the roots and repositories looked up since the cache was
+	// loaded; Save drops the others, which are no longer in the config.
+	seen map[string]bool
+}
+
+type projectCacheFile struct {
+	Version int                   `json:"version"`
+	Roots   map[string]cachedRoot `json:"roots"`
+	// Repos holds the linked worktrees by repository.
+	Repos map[string]cachedRepo `json:"repos"`
+}
+
+type cachedRepo struct {
+	Worktrees []cachedWorktree `json:"worktrees"`
+	MTimes    map[string]int64 `json:"mtimes"`
+}
+
+type cachedWorktree struct {
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+}
+
+type cachedRoot struct {
+	Dirs   []string         `json:"dirs"`
+	MTimes map[string]int64 `json:"mtimes"`
+}
+
+// LoadProjectCache reads the cache at path. A missing, unreadable or outdated
+// cache is no error, it starts empty; so does every cache when refresh is set.
+func LoadProjectCache(path string, refresh bool) *ProjectCache {
+	pc := &ProjectCache{
+		path: path,
+		file: projectCacheFile{
+			Version: projectCacheVersion,
+			Roots:   make(map[string]cachedRoot),
+			Repos:   make(map[string]cachedRepo),
+		},
+		seen: make(map[string]bool),
+	}
+	if refresh {
+		return pc
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return pc
+	}
+	var file projectCacheFile
+	if err := json.Unmarshal(b, &file); err != nil || file.Version != projectCacheVersion || file.Roots == nil || file.Repos == nil {
+		return pc
+	}
+	pc.file = file
+	return pc
+}
+
+// ClearProjectCache removes the cache at path, if there is one.
+func ClearProjectCache(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove project cache:%w", err)
+	}
+	return nil
+}
+
+func cacheKey(root string, depth int) string {
+	return fmt.Sprintf("%d,%s", depth, root)
+}
+
+// lookup returns the projects of root, and the directories listed to find
+// them, when none of those changed since.
+func (pc *ProjectCache) lookup(root string, depth int) ([]string, []string, bool) {
+	if pc == nil {
+		return nil, nil, false
+	}
+	pc.mu.Lock()
+	pc.seen[cacheKey(root, depth)] = true
+	cached, exists := pc.file.Roots[cacheKey(root, depth)]
+	pc.mu.Unlock()
+	if !exists {
+		return nil, nil, false
+	}
+
+	for dir, mtime := range cached.MTimes {
+		info, err := os.Stat(dir)
+		if err != nil || info.ModTime().UnixNano() != mtime {
+			return nil, nil, false
+		}
+	}
+	return cached.Dirs, slices.Sorted(maps.Keys(cached.MTimes)), true
+}
+
+// store caches the projects of root walked at walkedAt, unless a directory
+// changed within racyWindow of the walk: a change right after might not have
+// changed its time.
+func (pc *ProjectCache) store(root string, depth int, dirs []string, mtimes map[string]int64, walkedAt time.Time) {
+	if pc == nil {
+		return
+	}
+	for _, mtime := range mtimes {
+		if walkedAt.Sub(time.Unix(0, mtime)) < racyWindow {
+			return
+		}
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.seen[cacheKey(root, depth)] = true
+	pc.file.Roots[cacheKey(root, depth)] = cachedRoot{Dirs: dirs, MTimes: mtimes}
+	pc.dirty = true
+}
+
+// lookupWorktrees returns the linked worktrees of repo when mtimes, see
+// worktreeMTimes, are those they were listed with and none of them is gone.
+func (pc *ProjectCache) lookupWorktrees(repo string, mtimes map[string]int64) ([]git.Worktree, bool) {
+	if pc == nil {
+		return nil, false
+	}
+	pc.mu.Lock()
+	pc.seen[repoKey(repo)] = true
+	cached, exists := pc.file.Repos[repo]
+	pc.mu.Unlock()
+	if !exists || !maps.Equal(cached.MTimes, mtimes) {
+		return nil, false
+	}
+
+	worktrees := make([]git.Worktree, 0, len(cached.Worktrees))
+	for _, w := range cached.Worktrees {
+		// A worktree directory removed without `git worktree remove` is still in .git/worktrees.
+		if _, err := os.Stat(w.Path); err != nil {
+			return nil, false
+		}
+		worktrees = append(worktrees, git.Worktree{
+			Repository: types.NewString(repo),
+			Path:       types.NewString(w.Path),
+			Branch:     types.NewString(w.Branch),
+		})
+	}
+	return worktrees, true
+}
+
+// storeWorktrees caches the linked worktrees of repo listed at listedAt, like
+// store does the projects of a root.
+func (pc *ProjectCache) storeWorktrees(repo string, mtimes map[string]int64, worktrees []git.Worktree, listedAt time.Time) {
+	if pc == nil {
+		return
+	}
+	for _, mtime := range mtimes {
+		if listedAt.Sub(time.Unix(0, mtime)) < racyWindow {
+			return
+		}
+	}
+	cached := cachedRepo{Worktrees: make([]cachedWorktree, 0, len(worktrees)), MTimes: mtimes}
+	for _, w := range worktrees {
+		cached.Worktrees = append(cached.Worktrees, cachedWorktree{Path: w.Path.Value(), Branch: w.Branch.Value()})
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.seen[repoKey(repo)] = true
+	pc.file.Repos[repo] = cached
+	pc.dirty = true
+}
+
+// repoKey tells a repository apart from a root in ProjectCache.seen.
+func repoKey(repo string) string {
+	return "repo," + repo
+}
+
+// Save writes the cache back when anything was walked or a root is no longer
+// looked up. The file is replaced at once, so a concurrent run never reads
+// half of it.
+func (pc *ProjectCache) Save() error {
+	if pc == nil {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for key := range pc.file.Roots {
+		if !pc.seen[key] {
+			delete(pc.file.Roots, key)
+			pc.dirty = true
+		}
+	}
+	for repo := range pc.file.Repos {
+		if !pc.seen[repoKey(repo)] {
+			delete(pc.file.Repos, repo)
+			pc.dirty = true
+		}
+	}
+	if !pc.dirty {
+		return nil
+	}
+
+	b, err := json.Marshal(pc.file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pc.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory:%w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(pc.path), filepath.Base(pc.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write project cache:%w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write project cache:%w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write project cache:%w", err)
+	}
+	if err := os.Rename(tmp.Name(), pc.path); err != nil {
+		return fmt.Errorf("failed to write project cache:%w", err)
+	}
+	pc.dirty = false
+	return nil
+}
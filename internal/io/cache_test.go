@@ -0,0 +1,247 @@
+package io
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCacheFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "XDG_CACHE_HOME",
+			env:  map[string]string{"XDG_CACHE_HOME": "/xdg/cache"},
+			want: "/xdg/cache/tmux-sessionizer/projects.json",
+		},
+		{
+			name: "relative XDG_CACHE_HOME is ignored",
+			env:  map[string]string{"XDG_CACHE_HOME": "cache"},
+			want: filepath.Join(os.Getenv("HOME"), ".cache/tmux-sessionizer/projects.json"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := CacheFile(NewFiler(), func(key string) string { return tt.env[key] })
+			if err != nil {
+				t.Fatalf("CacheFile() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CacheFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigParser_parseEntries_TakesUnchangedRootsFromCache(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, d := range []string{"org/app", "org/tool"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory changed just now is not trusted to be cached.
+	age(t, filepath.Join(root, "org"), time.Hour)
+	cacheFile := filepath.Join(t.TempDir(), "projects.json")
+	reads := 0
+	parse := func(refresh bool) []string {
+		t.Helper()
+
+		cp := NewConfigParser().WithCache(LoadProjectCache(cacheFile, refresh))
+		cp.readDir = func(name string) ([]os.DirEntry, error) {
+			reads++
+			return os.ReadDir(name)
+		}
+		config := newConfig()
+		if err := cp.parseEntries(t.Context(), config, rootEntries(filepath.Join(root, "org")), NewFiler()); err != nil {
+			t.Fatalf("parseEntries() error = %v", err)
+		}
+		if err := cp.cache.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		projects := []string{}
+		for _, p := range config.Projects {
+			projects = append(projects, p.Value())
+		}
+		return projects
+	}
+
+	want := []string{filepath.Join(root, "org/app"), filepath.Join(root, "org/tool")}
+	if diff := cmp.Diff(want, parse(false)); diff != "" {
+		t.Errorf("first parse() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want, parse(false)); diff != "" || reads != 1 {
+		t.Errorf("cached parse() read %d directories, mismatch (-want +got):\n%s", reads, diff)
+	}
+
+	// A new project changes the time of the root; the clock of some file
+	// systems is too coarse to tell, so the time is set explicitly.
+	if err := os.Mkdir(filepath.Join(root, "org/new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	age(t, filepath.Join(root, "org"), time.Minute)
+	want = []string{filepath.Join(root, "org/app"), filepath.Join(root, "org/new"), filepath.Join(root, "org/tool")}
+	if diff := cmp.Diff(want, parse(false)); diff != "" || reads != 2 {
+		t.Errorf("parse() after change read %d directories, mismatch (-want +got):\n%s", reads, diff)
+	}
+
+	if diff := cmp.Diff(want, parse(true)); diff != "" || reads != 3 {
+		t.Errorf("refreshed parse() read %d directories, mismatch (-want +got):\n%s", reads, diff)
+	}
+}
+
+// age sets the time of dir to d ago.
+func age(t *testing.T, dir string, d time.Duration) {
+	t.Helper()
+
+	then := time.Now().Add(-d)
+	if err := os.Chtimes(dir, then, then); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProjectCache_store_SkipsRacyWalk(t *testing.T) {
+	t.Parallel()
+
+	walkedAt := time.Now()
+	pc := LoadProjectCache(filepath.Join(t.TempDir(), "projects.json"), false)
+	pc.store("/src", 1, []string{"/src/app"}, map[string]int64{"/src": walkedAt.Add(-time.Second).UnixNano()}, walkedAt)
+	if _, exists := pc.file.Roots[cacheKey("/src", 1)]; exists {
+		t.Error("store() cached a walk of a directory changed within the racy window")
+	}
+
+	pc.store("/src", 1, []string{"/src/app"}, map[string]int64{"/src": walkedAt.Add(-time.Minute).UnixNano()}, walkedAt)
+	if _, exists := pc.file.Roots[cacheKey("/src", 1)]; !exists {
+		t.Error("store() did not cache a walk of directories changed long before")
+	}
+}
+
+func TestProjectCache_Save_DropsRootsNotLookedUp(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	age(t, root, time.Hour)
+	cacheFile := filepath.Join(t.TempDir(), "projects.json")
+
+	pc := LoadProjectCache(cacheFile, false)
+	pc.store(root, 1, []string{}, map[string]int64{root: time.Now().Add(-time.Hour).UnixNano()}, time.Now())
+	pc.store("/removed", 1, []string{}, map[string]int64{}, time.Now())
+	if err := pc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Only root is still in the config.
+	pc = LoadProjectCache(cacheFile, false)
+	pc.lookup(root, 1)
+	if err := pc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got := slices.Sorted(maps.Keys(LoadProjectCache(cacheFile, false).file.Roots))
+	if diff := cmp.Diff([]string{cacheKey(root, 1)}, got); diff != "" {
+		t.Errorf("Save() roots mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProjectCache_lookupWorktrees_TakesUnchangedWorktrees(t *testing.T) {
+	t.Parallel()
+
+	repo, worktree := t.TempDir(), t.TempDir()
+	head := filepath.Join(repo, ".git", "worktrees", "feature", "HEAD")
+	if err := os.MkdirAll(filepath.Dir(head), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(head, []byte("ref: refs/heads/feature\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{head, filepath.Dir(filepath.Dir(head))} {
+		age(t, p, time.Hour)
+	}
+
+	mtimes, err := worktreeMTimes(repo)
+	if err != nil {
+		t.Fatalf("worktreeMTimes() error = %v", err)
+	}
+	want := []git.Worktree{{
+		Repository: types.NewString(repo),
+		Path:       types.NewString(worktree),
+		Branch:     types.NewString("feature"),
+	}}
+	pc := LoadProjectCache(filepath.Join(t.TempDir(), "projects.json"), false)
+	pc.storeWorktrees(repo, mtimes, want, time.Now())
+
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	got, fresh := pc.lookupWorktrees(repo, mtimes)
+	if diff := cmp.Diff(want, got, opt); !fresh || diff != "" {
+		t.Errorf("lookupWorktrees() fresh = %v, mismatch (-want +got):\n%s", fresh, diff)
+	}
+
+	// Switching the branch of the worktree rewrites its HEAD.
+	age(t, head, time.Minute)
+	switched, err := worktreeMTimes(repo)
+	if err != nil {
+		t.Fatalf("worktreeMTimes() error = %v", err)
+	}
+	if _, fresh := pc.lookupWorktrees(repo, switched); fresh {
+		t.Error("lookupWorktrees() fresh after a branch switch, want stale")
+	}
+
+	if err := os.Remove(worktree); err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh := pc.lookupWorktrees(repo, mtimes); fresh {
+		t.Error("lookupWorktrees() fresh with the worktree directory gone, want stale")
+	}
+}
+
+func TestLoadProjectCache_DropsOtherVersions(t *testing.T) {
+	t.Parallel()
+
+	cacheFile := filepath.Join(t.TempDir(), "projects.json")
+	content := `{"version":0,"roots":{"1,/src":{"dirs":["/src/app"],"mtimes":{}}}}`
+	if err := os.WriteFile(cacheFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, fresh := LoadProjectCache(cacheFile, false).lookup("/src", 1); fresh {
+		t.Error("expected a cache of another version to be dropped")
+	}
+}
+
+func TestClearProjectCache(t *testing.T) {
+	t.Parallel()
+
+	cacheFile := filepath.Join(t.TempDir(), "projects.json")
+	if err := os.WriteFile(cacheFile, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		// Clearing twice is fine, there is just nothing left to remove.
+		if err := ClearProjectCache(cacheFile); err != nil {
+			t.Fatalf("ClearProjectCache() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Errorf("expected cache to be removed, got %v", err)
+	}
+}
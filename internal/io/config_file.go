@@ -0,0 +1,82 @@
+package io
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// EnvConfigFile names the config file, unless --config is given.
+	EnvConfigFile = "TMUX_SESSIONIZER_CONFIG"
+	// LegacyConfigFile is where the config file lived before XDG support.
+	LegacyConfigFile = "~/.tmux-sessionizer"
+)
+
+type ConfigSource string
+
+const (
+	SourceFlag   ConfigSource = "--config"
+	SourceEnv    ConfigSource = "$" + EnvConfigFile
+	SourceXDG    ConfigSource = "XDG config directory"
+	SourceLegacy ConfigSource = "legacy " + LegacyConfigFile
+)
+
+// ConfigFile is the config file in use and what decided it.
+type ConfigFile struct {
+	Path   string
+	Source ConfigSource
+}
+
+// ResolveConfigFile picks the config file from flag, then $TMUX_SESSIONIZER_CONFIG,
+// then $XDG_CONFIG_HOME/tmux-sessionizer/config, then ~/.tmux-sessionizer.
+// The last two are only taken when they exist; when neither does, the XDG
+// location is returned, so `init` creates the file there.
+func ResolveConfigFile(filer *Filer, flag string, getenv func(string) string) (ConfigFile, error) {
+	candidates := []ConfigFile{
+		{Path: flag, Source: SourceFlag},
+		{Path: getenv(EnvConfigFile), Source: SourceEnv},
+	}
+	for _, c := range candidates {
+		if len(c.Path) > 0 {
+			return absConfigFile(filer, c)
+		}
+	}
+
+	xdg, err := absConfigFile(filer, ConfigFile{Path: xdgConfigFile(getenv), Source: SourceXDG})
+	if err != nil {
+		return ConfigFile{}, err
+	}
+	legacy, err := absConfigFile(filer, ConfigFile{Path: LegacyConfigFile, Source: SourceLegacy})
+	if err != nil {
+		return ConfigFile{}, err
+	}
+	for _, c := range []ConfigFile{xdg, legacy} {
+		if _, err := os.Stat(c.Path); !errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+	}
+	return xdg, nil
+}
+
+// xdgConfigFile follows the XDG base directory spec, which says to ignore a
+// relative $XDG_CONFIG_HOME.
+func xdgConfigFile(getenv func(string) string) string {
+	configHome := getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(configHome) {
+		configHome = "~/.config"
+	}
+	return filepath.Join(configHome, "tmux-sessionizer", "config")
+}
+
+func absConfigFile(filer *Filer, c ConfigFile) (ConfigFile, error) {
+	path, err := filer.ExpandTildeAsHomeDir(c.Path)
+	if err != nil {
+		return ConfigFile{}, err
+	}
+	if c.Path, err = filepath.Abs(path); err != nil {
+		return ConfigFile{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,93 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveConfigFile(t *testing.T) {
+	t.Parallel()
+
+	xdgHome := t.TempDir()
+	xdgFile := filepath.Join(xdgHome, "tmux-sessionizer", "config")
+	if err := os.MkdirAll(filepath.Dir(xdgFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(xdgFile, []byte(ConfigPrefix), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		flag string
+		env  map[string]string
+		want ConfigFile
+	}{
+		{
+			name: "flag wins over everything",
+			flag: "/etc/tmux-sessionizer",
+			env:  map[string]string{EnvConfigFile: "/env/config", "XDG_CONFIG_HOME": xdgHome},
+			want: ConfigFile{Path: "/etc/tmux-sessionizer", Source: SourceFlag},
+		},
+		{
+			name: "environment variable wins over XDG",
+			env:  map[string]string{EnvConfigFile: "/env/config", "XDG_CONFIG_HOME": xdgHome},
+			want: ConfigFile{Path: "/env/config", Source: SourceEnv},
+		},
+		{
+			name: "existing XDG config",
+			env:  map[string]string{"XDG_CONFIG_HOME": xdgHome},
+			want: ConfigFile{Path: xdgFile, Source: SourceXDG},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ResolveConfigFile(NewFiler(), tt.flag, func(key string) string { return tt.env[key] })
+			if err != nil {
+				t.Fatalf("ResolveConfigFile() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ResolveConfigFile() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// HOME is set, so the test must not run in parallel.
+func TestResolveConfigFile_WithoutXDGConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	xdgHome := t.TempDir()
+	getenv := func(key string) string {
+		return map[string]string{"XDG_CONFIG_HOME": xdgHome}[key]
+	}
+
+	// Neither file exists: the XDG location is where init creates one.
+	got, err := ResolveConfigFile(NewFiler(), "", getenv)
+	if err != nil {
+		t.Fatalf("ResolveConfigFile() error = %v", err)
+	}
+	want := ConfigFile{Path: filepath.Join(xdgHome, "tmux-sessionizer", "config"), Source: SourceXDG}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ResolveConfigFile() without any config mismatch (-want +got):\n%s", diff)
+	}
+
+	legacy := filepath.Join(home, ".tmux-sessionizer")
+	if err := os.WriteFile(legacy, []byte(ConfigPrefix), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ResolveConfigFile(NewFiler(), "", getenv)
+	if err != nil {
+		t.Fatalf("ResolveConfigFile() error = %v", err)
+	}
+	want = ConfigFile{Path: legacy, Source: SourceLegacy}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ResolveConfigFile() with a legacy config mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,114 +1,796 @@
 package io
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/hook"
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
+	// ConfigPrefix lists roots whose immediate subdirectories are projects.
 	ConfigPrefix = "default="
+	// RootPrefix lists roots whose directories up to depth levels below are
+	// projects, written as root=<depth>,<path>,<path>...; default= is root=1.
+	RootPrefix = "root="
+	// ProjectPrefix lists directories that are projects themselves.
+	ProjectPrefix = "project="
+	AliasPrefix   = "alias="
+	// WorktreePrefix sets the directory new git worktrees are created in.
+	WorktreePrefix = "worktree="
+	// ClonePrefix sets the directory `tmux-sessionizer clone` clones into.
+	ClonePrefix = "clone="
+	// WorkspacePrefix names a group of projects opened together, written as
+	// workspace=<name>,<path>,<path>...
+	WorkspacePrefix = "workspace="
+	// HookPrefix runs a command around a session event, written as
+	// hook=<event>,<command>. The command may contain commas.
+	HookPrefix = "hook="
+	// HookTimeoutPrefix bounds how long a hook may run, e.g. hook-timeout=1m.
+	HookTimeoutPrefix = "hook-timeout="
+	// DiscoveryTimeoutPrefix bounds how long a single root may take to read,
+	// e.g. discovery-timeout=10s.
+	DiscoveryTimeoutPrefix = "discovery-timeout="
+	// DefaultCloneRoot is used when the config file does not set clone=.
+	DefaultCloneRoot = "~/ghq"
+)
+
+// Prefixes lists every key of the config file, in the order they are
+// documented.
+var Prefixes = []string{
+	ConfigPrefix, RootPrefix, ProjectPrefix, AliasPrefix, WorktreePrefix, ClonePrefix, WorkspacePrefix,
+	EnvPrefix, HookPrefix, HookTimeoutPrefix, DiscoveryTimeoutPrefix, IncludePrefix, LabelPrefix,
+}
+
+// HasKey tells whether line sets any key of the config file. Spaces around
+// the key are allowed, as include = [...] has them.
+func HasKey(line string) bool {
+	key, _, found := strings.Cut(line, "=")
+	return found && slices.Contains(Prefixes, strings.TrimSpace(key)+"=")
+}
+
+const (
+	DefaultDiscoveryConcurrency = 8
+	// DefaultRootTimeout is long enough for a large local root, and short
+	// enough not to notice a hung network mount for long.
+	DefaultRootTimeout = 3 * time.Second
+)
+
+var (
+	ErrInvalidDepth   = errors.New("root depth must be a positive number")
+	ErrInvalidTimeout = errors.New("discovery timeout must be a positive duration")
+	// ErrDiscoveryStopped, as the cause of cancelling the context given to
+	// ReadConfig, stops discovery without failing: the config holds the
+	// projects found until then, e.g. once one of them was picked.
+	ErrDiscoveryStopped = errors.New("discovery stopped")
 )
 
 type Config struct {
 	// Registered holds the normalized root directories listed in the config
-	// file, while Projects holds their immediate subdirectories. Duplicate
+	// file, while Projects holds the projects found in them. Duplicate
 	// registration must be checked against Registered, not Projects.
 	Registered []types.String
-	Projects   []types.String
+	// RegisteredProjects holds the normalized project= entries, which are
+	// projects themselves rather than roots.
+	RegisteredProjects []types.String
+	// SlowRoots holds the roots and projects skipped because reading them
+	// took longer than DiscoveryTimeout.
+	SlowRoots []types.String
+	// DiscoveryTimeout bounds how long a single root may take to read.
+	DiscoveryTimeout time.Duration
+	// Skipped holds why lines, or entries of a line, were left out, each
+	// error naming the file and line, so a typo cannot break every command.
+	Skipped []error
+	// ListedDirs holds the directories listed to find the projects of the
+	// roots; the projects only change when one of them does.
+	ListedDirs []types.String
+	// Files holds the config file and every file it includes.
+	Files    []string
+	Projects []types.String
+	// Aliases maps a project path to the session name it was renamed to,
+	// so a recreated session keeps the name the user chose.
+	Aliases map[types.String]types.String
+	// Worktrees maps the path of a linked git worktree listed in Projects to
+	// the worktree, so its session can be named after repository and branch.
+	Worktrees map[types.String]git.Worktree
+	// WorktreeRoot is where `tmux-sessionizer worktree` creates worktrees.
+	// It is empty unless the config file sets it.
+	WorktreeRoot types.String
+	// CloneRoot is where `tmux-sessionizer clone` clones repositories into,
+	// laid out as <CloneRoot>/<host>/<owner>/<repository>.
+	CloneRoot types.String
+	// Workspaces maps a workspace name to its projects, in config order.
+	Workspaces map[string][]types.String
+	// Env holds the environment variables new sessions start with, see Environ.
+	Env []Env
+	// Hooks maps an event to the commands run for it, in config order.
+	Hooks       map[hook.Event][]string
+	HookTimeout time.Duration
+	// Label is how the picker shows projects, see LabelOf.
+	Label Label
 }
 
 func newConfig() *Config {
 	return &Config{
-		Registered: []types.String{},
-		Projects:   []types.String{},
+		Registered:         []types.String{},
+		RegisteredProjects: []types.String{},
+		SlowRoots:          []types.String{},
+		Skipped:            []error{},
+		ListedDirs:         []types.String{},
+		Files:              []string{},
+		Projects:           []types.String{},
+		Aliases:            make(map[types.String]types.String),
+		Worktrees:          make(map[types.String]git.Worktree),
+		Workspaces:         make(map[string][]types.String),
+		Env:                []Env{},
+		Hooks:              make(map[hook.Event][]string),
+		Label:              Label{Style: LabelPath},
 	}
 }
 
-type ConfigParser struct{}
+type ConfigParser struct {
+	git *git.Git
+	// concurrency bounds how many roots are walked at once.
+	concurrency int
+	// rootTimeout bounds the walk of a single root unless the config file
+	// sets discovery-timeout=.
+	rootTimeout time.Duration
+	readDir     func(name string) ([]os.DirEntry, error)
+	onRoots     func(config *Config)
+	onProject   func(config *Config, project types.String)
+	cache       *ProjectCache
+	index       *ProjectIndex
+}
 
 func NewConfigParser() *ConfigParser {
-	return &ConfigParser{}
+	return &ConfigParser{
+		git:         git.NewGit(),
+		concurrency: DefaultDiscoveryConcurrency,
+		rootTimeout: DefaultRootTimeout,
+		readDir:     os.ReadDir,
+	}
+}
+
+// OnRoots makes ReadConfig call onRoots once the label and roots of the
+// config are read, before OnProject is given any project, so a picker can
+// label what it shows before discovery starts.
+func (c *ConfigParser) OnRoots(onRoots func(config *Config)) *ConfigParser {
+	c.onRoots = onRoots
+	return c
+}
+
+// OnProject makes ReadConfig hand every project to onProject as soon as it is
+// found, in the order of Config.Projects, so a picker can show it before
+// discovery has finished. The config is still being read then, but its Label
+// and roots are set. onProject is called from a single goroutine.
+func (c *ConfigParser) OnProject(onProject func(config *Config, project types.String)) *ConfigParser {
+	c.onProject = onProject
+	return c
 }
 
-func (c *ConfigParser) ReadConfig(filer *Filer, configFileAbs string) (*Config, error) {
-	f, err := os.Open(configFileAbs)
+// WithCache makes ReadConfig take the projects of unchanged roots from cache
+// and save what it walked back to it.
+func (c *ConfigParser) WithCache(cache *ProjectCache) *ConfigParser {
+	c.cache = cache
+	return c
+}
+
+func (c *ConfigParser) ReadConfig(ctx context.Context, filer *Filer, configFileAbs string) (*Config, error) {
+	lines, err := ReadConfigLines(filer, configFileAbs)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	projectList := []string{}
+	entries := []rootEntry{}
+	aliasList := []ConfigLine{}
+	workspaceList := []ConfigLine{}
+	envList := []ConfigLine{}
+	hookList := []ConfigLine{}
+	hookTimeout, discoveryTimeout := ConfigLine{}, ConfigLine{}
+	label := ConfigLine{Text: string(LabelPath)}
+	worktreeRoot, cloneRoot := ConfigLine{}, ConfigLine{Text: DefaultCloneRoot}
+
+	for _, l := range lines {
+		line := l.Text
+		switch {
+		case strings.HasPrefix(line, ConfigPrefix):
+			for _, p := range strings.Split(strings.TrimPrefix(line, ConfigPrefix), ",") {
+				entries = append(entries, rootEntry{line: l.withText(p), depth: 1})
+			}
+		case strings.HasPrefix(line, RootPrefix):
+			fields := strings.Split(strings.TrimPrefix(line, RootPrefix), ",")
+			depth, err := ParseDepth(fields[0])
+			if err != nil {
+				return nil, l.wrap(err)
+			}
+			for _, p := range fields[1:] {
+				entries = append(entries, rootEntry{line: l.withText(p), depth: depth})
+			}
+		case strings.HasPrefix(line, ProjectPrefix):
+			for _, p := range strings.Split(strings.TrimPrefix(line, ProjectPrefix), ",") {
+				entries = append(entries, rootEntry{line: l.withText(p)})
+			}
+		case strings.HasPrefix(line, AliasPrefix):
+			aliasList = append(aliasList, l.withText(strings.TrimPrefix(line, AliasPrefix)))
+		case strings.HasPrefix(line, WorktreePrefix):
+			worktreeRoot = l.withText(strings.TrimPrefix(line, WorktreePrefix))
+		case strings.HasPrefix(line, WorkspacePrefix):
+			workspaceList = append(workspaceList, l.withText(strings.TrimPrefix(line, WorkspacePrefix)))
+		case strings.HasPrefix(line, EnvPrefix):
+			envList = append(envList, l.withText(strings.TrimPrefix(line, EnvPrefix)))
+		case strings.HasPrefix(line, HookPrefix):
+			hookList = append(hookList, l.withText(strings.TrimPrefix(line, HookPrefix)))
+		case strings.HasPrefix(line, HookTimeoutPrefix):
+			hookTimeout = l.withText(strings.TrimPrefix(line, HookTimeoutPrefix))
+		case strings.HasPrefix(line, DiscoveryTimeoutPrefix):
+			discoveryTimeout = l.withText(strings.TrimPrefix(line, DiscoveryTimeoutPrefix))
+		case strings.HasPrefix(line, ClonePrefix):
+			cloneRoot = l.withText(strings.TrimPrefix(line, ClonePrefix))
+			// NOTE: a blank clone= would clone into the current directory.
+			if len(strings.TrimSpace(cloneRoot.Text)) == 0 {
+				cloneRoot.Text = DefaultCloneRoot
+			}
+		case strings.HasPrefix(line, LabelPrefix):
+			label = l.withText(strings.TrimPrefix(line, LabelPrefix))
+		}
+	}
+
+	// The label is read before the projects, which are shown as they are found.
+	config := newConfig()
+	if config.Label, err = c.parseLabel(filer, label.Text); err != nil {
+		return nil, label.wrap(err)
+	}
+	if len(strings.TrimSpace(discoveryTimeout.Text)) > 0 {
+		if config.DiscoveryTimeout, err = ParseDiscoveryTimeout(discoveryTimeout.Text); err != nil {
+			return nil, discoveryTimeout.wrap(err)
+		}
+	}
+	if err := c.parseEntries(ctx, config, entries, filer); err != nil {
+		return nil, err
+	}
+	// An empty included file has no lines, but the config file is read anyway.
+	config.Files = append(config.Files, configFileAbs)
+	for _, l := range lines {
+		if !slices.Contains(config.Files, l.File) {
+			config.Files = append(config.Files, l.File)
+		}
+	}
+	// NOTE: the cache only saves time, failing to write it must not fail the command.
+	// A stopped discovery has not seen every root, whose entries Save would prune.
+	if !errors.Is(context.Cause(ctx), ErrDiscoveryStopped) {
+		_ = c.cache.Save()
+	}
+	c.parseAliases(config, aliasList)
+	if err := c.parseWorkspaces(config, workspaceList, filer); err != nil {
+		return nil, err
+	}
+	if err := c.parseEnvs(config, envList, filer); err != nil {
+		return nil, err
+	}
+	if err := c.parseHooks(config, hookList, hookTimeout); err != nil {
+		return nil, err
+	}
+
+	if config.WorktreeRoot, err = c.parseDir(filer, worktreeRoot.Text); err != nil {
+		return nil, worktreeRoot.wrap(err)
+	}
+	if config.CloneRoot, err = c.parseDir(filer, cloneRoot.Text); err != nil {
+		return nil, cloneRoot.wrap(err)
+	}
+
+	return config, nil
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, ConfigPrefix) {
+// parseWorkspaces reads "name,path,path..." entries. Paths are normalized like
+// registered entries, but each one is a project on its own.
+func (c *ConfigParser) parseWorkspaces(config *Config, workspaceList []ConfigLine, filer *Filer) error {
+	for _, w := range workspaceList {
+		fields := strings.Split(w.Text, ",")
+		name := strings.TrimSpace(fields[0])
+		if len(name) == 0 {
 			continue
 		}
 
-		seq := strings.Split(strings.TrimPrefix(line, ConfigPrefix), ",")
-		projectList = append(projectList, seq...)
+		projects := []types.String{}
+		for _, f := range fields[1:] {
+			project, err := c.parseDir(filer, f)
+			if err != nil {
+				return w.wrap(err)
+			}
+			if len(project.Value()) > 0 {
+				projects = append(projects, project)
+			}
+		}
+		config.Workspaces[name] = projects
 	}
+	return nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// parseEnvs reads "KEY=VALUE" and "path,KEY=VALUE" entries.
+func (c *ConfigParser) parseEnvs(config *Config, envList []ConfigLine, filer *Filer) error {
+	for _, raw := range envList {
+		scope, e, err := SplitEnv(raw.Text)
+		if err != nil {
+			return raw.wrap(err)
+		}
+		if e.Scope, err = c.parseDir(filer, scope); err != nil {
+			return raw.wrap(err)
+		}
+		config.Env = append(config.Env, e)
 	}
+	return nil
+}
 
-	return c.parse(projectList, filer)
+// parseHooks reads "event,command" entries and the hook timeout.
+func (c *ConfigParser) parseHooks(config *Config, hookList []ConfigLine, timeout ConfigLine) error {
+	for _, h := range hookList {
+		rawEvent, script, _ := strings.Cut(h.Text, ",")
+		event, err := hook.ParseEvent(strings.TrimSpace(rawEvent))
+		if err != nil {
+			config.Skipped = append(config.Skipped, h.wrap(err))
+			continue
+		}
+		if script = strings.TrimSpace(script); len(script) > 0 {
+			config.Hooks[event] = append(config.Hooks[event], script)
+		}
+	}
+
+	if value := strings.TrimSpace(timeout.Text); len(value) > 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return timeout.wrap(fmt.Errorf("invalid %s%s:%w", HookTimeoutPrefix, value, err))
+		}
+		config.HookTimeout = d
+	}
+	return nil
 }
 
-func (c *ConfigParser) parse(projectList []string, filer *Filer) (*Config, error) {
-	config := newConfig()
+// parseDir normalizes the value of a key holding a single directory. A blank
+// value stays empty, so callers can tell that the key is not set.
+func (c *ConfigParser) parseDir(filer *Filer, dir string) (types.String, error) {
+	dir = strings.TrimSpace(dir)
+	if len(dir) == 0 {
+		return types.String{}, nil
+	}
+
+	dir, err := filer.ExpandTildeAsHomeDir(dir)
+	if err != nil {
+		return types.String{}, err
+	}
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return types.String{}, err
+	}
+	return types.NewString(absPath), nil
+}
+
+// rootEntry is an entry of default=, root= or project=. A depth of 0 makes
+// the entry a project itself.
+type rootEntry struct {
+	line  ConfigLine
+	depth int
+}
+
+// ParseDepth reads the depth of a root= line, which must be at least 1.
+func ParseDepth(raw string) (int, error) {
+	depth, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || depth < 1 {
+		return 0, fmt.Errorf("%q:%w", raw, ErrInvalidDepth)
+	}
+	return depth, nil
+}
+
+// ParseDiscoveryTimeout reads the value of discovery-timeout=, which must be
+// positive.
+func ParseDiscoveryTimeout(raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q:%w", raw, ErrInvalidTimeout)
+	}
+	return d, nil
+}
 
-	for _, p := range projectList {
-		tp := strings.TrimSpace(p)
+// parseLabel reads the value of label=, resolving the home directory labels
+// are shortened with.
+func (c *ConfigParser) parseLabel(filer *Filer, raw string) (Label, error) {
+	label, err := ParseLabel(raw)
+	if err != nil {
+		return Label{}, err
+	}
+	// NOTE: without a home directory, paths are simply not shortened.
+	label.home, _ = filer.ExpandTildeAsHomeDir("~")
+	return label, nil
+}
+
+// parseEntries adds the roots and projects of entries to config. A root is
+// walked for config.DiscoveryTimeout at most, or the timeout of the parser
+// when the config file does not set one.
+func (c *ConfigParser) parseEntries(ctx context.Context, config *Config, entries []rootEntry, filer *Filer) error {
+	if config.DiscoveryTimeout == 0 {
+		config.DiscoveryTimeout = c.rootTimeout
+	}
+	walks := []rootWalk{}
+
+	for _, e := range entries {
+		tp := strings.TrimSpace(e.line.Text)
 		if len(tp) == 0 {
 			continue
 		}
 
-		tp, err := filer.ExpandTildeAsHomeDir(tp)
-		if err != nil {
-			return nil, err
+		// A glob may stand for several roots, each read on its own.
+		paths, err := filer.ResolveRoots(tp)
+		if errors.Is(err, ErrUndefinedEnv) {
+			// NOTE: e.g. $WORK is only set on the work machine sharing this config.
+			config.Skipped = append(config.Skipped, e.line.wrap(err))
+			continue
+		} else if err != nil {
+			return e.line.wrap(err)
 		}
+		for _, absPath := range paths {
+			// Record the entry even when its directory is gone: it still lives in
+			// the config file, so re-registering it would duplicate the line.
+			if e.depth == 0 {
+				config.RegisteredProjects = append(config.RegisteredProjects, types.NewString(absPath))
+			} else {
+				config.Registered = append(config.Registered, types.NewString(absPath))
+			}
+			walks = append(walks, rootWalk{line: e.line, absPath: absPath, depth: e.depth})
+		}
+	}
 
-		absPath, err := filepath.Abs(tp)
-		if err != nil {
-			return nil, err
+	if c.onRoots != nil {
+		c.onRoots(config)
+	}
+	if c.index != nil {
+		c.applyIndex(config)
+		return nil
+	}
+	return c.discover(ctx, config, filer, walks)
+}
+
+// rootWalk is a single directory of an entry to look for projects in.
+type rootWalk struct {
+	line    ConfigLine
+	absPath string
+	depth   int
+	walked
+	err error
+}
+
+// walked is what walkRoot found under a root.
+type walked struct {
+	found  []candidate
+	listed []string
+	// skipped holds why directories could not be listed.
+	skipped []error
+}
+
+// candidate is a directory found under a root, told apart as a repository
+// with its linked worktrees, a linked worktree, or a plain project.
+type candidate struct {
+	path string
+	// linked is set for a worktree linked to a repository elsewhere.
+	linked bool
+	// worktrees are the linked worktrees of a repository.
+	worktrees []git.Worktree
+}
+
+// discover walks the roots concurrently and adds their projects to config in
+// config order, each root as soon as it and the ones before it are done. A
+// root that takes longer than config.DiscoveryTimeout, e.g. on a hung network mount, is
+// skipped and recorded in config.SlowRoots rather than blocking everything else.
+// Once ctx is cancelled with ErrDiscoveryStopped, the roots not done by then
+// are left out.
+func (c *ConfigParser) discover(ctx context.Context, config *Config, filer *Filer, walks []rootWalk) error {
+	done := make([]chan struct{}, len(walks))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	eg := new(errgroup.Group)
+	eg.SetLimit(c.concurrency)
+	// Go blocks once the limit is reached, while results are taken below.
+	go func() {
+		for i := range walks {
+			eg.Go(func() error {
+				defer close(done[i])
+				walks[i].walked, walks[i].err = c.walkRoot(ctx, filer, walks[i].absPath, walks[i].depth, config.DiscoveryTimeout)
+				return nil
+			})
 		}
+	}()
 
-		// Record the entry even when its directory is gone: it still lives in
-		// the config file, so re-registering it would duplicate the line.
-		config.Registered = append(config.Registered, types.NewString(absPath))
+	grouper := newWorktreeGrouper(c, config)
+	var err error
+	for i := range walks {
+		<-done[i]
+		w := walks[i]
+		switch {
+		case err != nil:
+			// Keep waiting, so no walk outlives ReadConfig.
+		case errors.Is(context.Cause(ctx), ErrDiscoveryStopped):
+			// The roots walked until then are kept.
+		case ctx.Err() != nil:
+			err = ctx.Err()
+		case errors.Is(w.err, context.DeadlineExceeded):
+			config.SlowRoots = append(config.SlowRoots, types.NewString(w.absPath))
+		case w.err != nil:
+			err = w.line.wrap(w.err)
+		default:
+			for _, dir := range w.listed {
+				config.ListedDirs = append(config.ListedDirs, types.NewString(dir))
+			}
+			for _, skipped := range w.skipped {
+				config.Skipped = append(config.Skipped, w.line.wrap(skipped))
+			}
+			grouper.add(w.found)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	grouper.flush()
+	return nil
+}
+
+// walkRoot returns absPath itself when depth is 0, and the directories up to
+// depth levels below it otherwise, from the cache when it is still fresh,
+// along with the directories listed to find them. The worktrees of every
+// repository are listed within the same timeout, so roots full of
+// repositories are handled concurrently too. A hung stat or read cannot be
+// interrupted, so it is left behind when the timeout expires. A directory
+// that cannot be listed, the root or one below it, is skipped and recorded in
+// walked.skipped; the error is only about ctx.
+func (c *ConfigParser) walkRoot(ctx context.Context, filer *Filer, absPath string, depth int, timeout time.Duration) (walked, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
+	type result struct {
+		walked
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
 		if err := filer.Exists(absPath); err != nil {
 			// NOTE: registered directory might be deleted, we need to skip in this case.
+			done <- result{}
+			return
+		}
+		if depth == 0 {
+			done <- result{walked: walked{found: c.classify(ctx, []string{absPath})}}
+			return
+		}
+		if dirs, listed, fresh := c.cache.lookup(absPath, depth); fresh {
+			done <- result{walked: walked{found: c.classify(ctx, dirs), listed: listed}}
+			return
+		}
+
+		w := &dirWalk{mtimes: make(map[string]int64)}
+		walkedAt := time.Now()
+		dirs := c.readDirs(ctx, w, absPath, depth)
+		if err := ctx.Err(); err != nil {
+			done <- result{err: err}
+			return
+		}
+		// A directory skipped as unreadable might become readable without
+		// changing any time, so such a walk is not cached.
+		if len(w.skipped) == 0 {
+			c.cache.store(absPath, depth, dirs, w.mtimes, walkedAt)
+		}
+		done <- result{walked: walked{found: c.classify(ctx, dirs), listed: w.listed(), skipped: w.skipped}}
+	}()
+
+	select {
+	case r := <-done:
+		return r.walked, r.err
+	case <-ctx.Done():
+		return walked{}, ctx.Err()
+	}
+}
+
+// dirWalk records what readDirs saw for the cache.
+type dirWalk struct {
+	// mtimes holds the modification time of every directory listed.
+	mtimes map[string]int64
+	// skipped holds why directories could not be listed.
+	skipped []error
+}
+
+func (w *dirWalk) listed() []string {
+	return slices.Sorted(maps.Keys(w.mtimes))
+}
+
+// readDirs lists the directories under dir, each followed by its own
+// subdirectories while depth allows. A directory that cannot be listed is
+// recorded in w.skipped, while a subdirectory is still a project itself. It
+// gives up once ctx is done, which the caller checks.
+func (c *ConfigParser) readDirs(ctx context.Context, w *dirWalk, dir string, depth int) []string {
+	if ctx.Err() != nil {
+		return nil
+	}
+	// The time is taken before listing, so a change while listing is seen
+	// as a change next time.
+	info, err := os.Stat(dir)
+	if err != nil {
+		w.skipped = append(w.skipped, err)
+		return nil
+	}
+	entries, err := c.readDir(dir)
+	if err != nil {
+		w.skipped = append(w.skipped, err)
+		return nil
+	}
+	w.mtimes[dir] = info.ModTime().UnixNano()
+
+	dirs := []string{}
+	for _, e := range entries {
+		// The entry type comes with the listing, so no stat per child is needed.
+		if !e.IsDir() {
 			continue
 		}
+		path := filepath.Join(dir, e.Name())
+		dirs = append(dirs, path)
+
+		if depth > 1 {
+			dirs = append(dirs, c.readDirs(ctx, w, path, depth-1)...)
+		}
+	}
+	return dirs
+}
+
+// classify tells the repositories and linked worktrees among dirs apart,
+// listing the worktrees of each repository.
+func (c *ConfigParser) classify(ctx context.Context, dirs []string) []candidate {
+	found := make([]candidate, 0, len(dirs))
+	for _, path := range dirs {
+		switch {
+		case c.git.IsMainWorktree(path):
+			found = append(found, candidate{path: path, worktrees: c.worktreesOf(ctx, path)})
+		case c.git.IsLinkedWorktree(path):
+			found = append(found, candidate{path: path, linked: true})
+		default:
+			found = append(found, candidate{path: path})
+		}
+	}
+	return found
+}
+
+// worktreesOf lists the linked worktrees of repo. git is only asked when
+// something changed in .git/worktrees since it was last, so a root full of
+// repositories costs a few stats per repository rather than a git call.
+func (c *ConfigParser) worktreesOf(ctx context.Context, repo string) []git.Worktree {
+	listedAt := time.Now()
+	mtimes, err := worktreeMTimes(repo)
+	if errors.Is(err, os.ErrNotExist) {
+		// git creates .git/worktrees with the first linked worktree.
+		return []git.Worktree{}
+	}
+	if worktrees, fresh := c.cache.lookupWorktrees(repo, mtimes); err == nil && fresh {
+		return worktrees
+	}
+
+	worktrees, listErr := c.git.ListWorktrees(ctx, repo)
+	if listErr != nil {
+		// NOTE: git might be missing or the repository broken, the repository is still a project.
+		return worktrees
+	}
+	if err == nil {
+		c.cache.storeWorktrees(repo, mtimes, worktrees, listedAt)
+	}
+	return worktrees
+}
 
-		entries, err := os.ReadDir(absPath)
+// worktreeMTimes returns the modification times of .git/worktrees of repo and
+// of the HEAD of each worktree in it, which change when a worktree is added or
+// removed, or switches branch.
+func worktreeMTimes(repo string) (map[string]int64, error) {
+	dir := filepath.Join(repo, ".git", "worktrees")
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	mtimes := map[string]int64{dir: info.ModTime().UnixNano()}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		head := filepath.Join(dir, e.Name(), "HEAD")
+		info, err := os.Stat(head)
 		if err != nil {
 			return nil, err
 		}
+		mtimes[head] = info.ModTime().UnixNano()
+	}
+	return mtimes, nil
+}
 
-		for _, e := range entries {
-			if !e.IsDir() {
-				continue
-			}
-			path := filepath.Join(absPath, e.Name())
-			if err := filer.Exists(path); err != nil {
-				return nil, err
-			}
-			c.createProjects(config, path)
+// worktreeGrouper adds candidates to the projects, each repository followed
+// by its linked worktrees. A worktree found under a root by itself is listed
+// only under its repository, and worktrees outside every root are found this
+// way too. Candidates come root by root, so a linked worktree is held back
+// until the end, in case its repository shows up later.
+type worktreeGrouper struct {
+	c       *ConfigParser
+	config  *Config
+	seen    map[string]struct{}
+	pending []string
+}
+
+func newWorktreeGrouper(c *ConfigParser, config *Config) *worktreeGrouper {
+	return &worktreeGrouper{
+		c:      c,
+		config: config,
+		seen:   make(map[string]struct{}),
+	}
+}
+
+func (g *worktreeGrouper) add(candidates []candidate) {
+	for _, cand := range candidates {
+		if _, exists := g.config.Worktrees[types.NewString(cand.path)]; exists {
+			continue
+		}
+		if cand.linked {
+			g.pending = append(g.pending, cand.path)
+			continue
+		}
+
+		g.addProject(cand.path)
+		for _, w := range cand.worktrees {
+			g.config.Worktrees[w.Path] = w
+			g.addProject(w.Path.Value())
 		}
 	}
+}
 
-	return config, nil
+// flush adds the linked worktrees whose repository never showed up.
+func (g *worktreeGrouper) flush() {
+	for _, path := range g.pending {
+		if _, exists := g.config.Worktrees[types.NewString(path)]; !exists {
+			g.addProject(path)
+		}
+	}
+}
+
+func (g *worktreeGrouper) addProject(path string) {
+	if _, exists := g.seen[path]; exists {
+		return
+	}
+	g.seen[path] = struct{}{}
+	g.c.createProjects(g.config, path)
+}
+
+// parseAliases reads "path,name" pairs. A later alias for the same path wins,
+// which keeps the file readable even if it was edited by hand.
+func (c *ConfigParser) parseAliases(config *Config, aliasList []ConfigLine) {
+	splitCnt := 2
+	for _, a := range aliasList {
+		parts := strings.SplitN(a.Text, ",", splitCnt)
+		if len(parts) != splitCnt {
+			continue
+		}
+		path, name := types.NewString(parts[0]), types.NewString(parts[1])
+		if len(path.Value()) == 0 || len(name.Value()) == 0 {
+			continue
+		}
+		config.Aliases[path] = name
+	}
 }
 
 func (c *ConfigParser) createProjects(config *Config, path string) {
-	config.Projects = append(config.Projects, types.NewString(path))
+	project := types.NewString(path)
+	config.Projects = append(config.Projects, project)
+	if c.onProject != nil {
+		c.onProject(config, project)
+	}
 }
@@ -1,11 +1,16 @@
 package io
 
 import (
+	"context"
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/hook"
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	testutils "github.com/TlexCypher/my-tmux-sessionizer/test_utils"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -13,11 +18,20 @@ func setupHomeEnv() {
 	os.Setenv("HOME", "/tmp/tmuxsessionizer")
 }
 
-func Test_ConfigParser_parse(t *testing.T) {
+// rootEntries turns texts into default= entries without a place.
+func rootEntries(texts ...string) []rootEntry {
+	entries := make([]rootEntry, 0, len(texts))
+	for _, text := range texts {
+		entries = append(entries, rootEntry{line: ConfigLine{Text: text}, depth: 1})
+	}
+	return entries
+}
+
+func Test_ConfigParser_parseEntries(t *testing.T) {
 	setupHomeEnv()
 	t.Parallel()
 
-	// parse returns the immediate subdirectories of each registered entry,
+	// parseEntries returns the immediate subdirectories of each registered entry,
 	// so rebuild the fixture tree from scratch — otherwise the results
 	// would depend on leftovers in /tmp.
 	if err := os.RemoveAll("/tmp/tmuxsessionizer"); err != nil {
@@ -105,7 +119,8 @@ func Test_ConfigParser_parse(t *testing.T) {
 			t.Parallel()
 
 			cp := NewConfigParser()
-			got, err := cp.parse(tt.projectList, NewFiler())
+			got := newConfig()
+			err := cp.parseEntries(t.Context(), got, rootEntries(tt.projectList...), NewFiler())
 
 			opts := []cmp.Option{
 				cmp.Comparer(func(a, b types.String) bool {
@@ -113,15 +128,496 @@ func Test_ConfigParser_parse(t *testing.T) {
 				}),
 			}
 			if diff := cmp.Diff(tt.want.Registered, got.Registered, opts...); diff != "" {
-				t.Errorf("ConfigParser.parse() Registered mismatch (-want +got):\n%s", diff)
+				t.Errorf("ConfigParser.parseEntries() Registered mismatch (-want +got):\n%s", diff)
 			}
 			if diff := cmp.Diff(tt.want.Projects, got.Projects, opts...); diff != "" {
-				t.Errorf("ConfigParser.parse() Projects mismatch (-want +got):\n%s", diff)
+				t.Errorf("ConfigParser.parseEntries() Projects mismatch (-want +got):\n%s", diff)
 			}
 
 			if !errors.Is(tt.wantErr, err) {
-				t.Errorf("ConfigParser.parse() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ConfigParser.parseEntries() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func Test_ConfigParser_parseEntries_GroupsWorktreesUnderRepository(t *testing.T) {
+	t.Parallel()
+
+	// Resolve symlinks up front: git reports worktree paths resolved
+	// (e.g. /var -> /private/var on macOS).
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := filepath.Join(root, "app")
+	testutils.InitGitRepo(t, repo)
+	inside, outside := filepath.Join(root, "app-feature"), filepath.Join(elsewhere, "review")
+	testutils.Git(t, repo, "worktree", "add", "-q", "-b", "feature", inside)
+	testutils.Git(t, repo, "worktree", "add", "-q", "-b", "review", outside)
+	if err := os.Mkdir(filepath.Join(root, "blog"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := newConfig()
+	if err := NewConfigParser().parseEntries(t.Context(), got, rootEntries(root), NewFiler()); err != nil {
+		t.Fatalf("parseEntries() error = %v", err)
+	}
+
+	want := []string{repo, inside, outside, filepath.Join(root, "blog")}
+	projects := make([]string, 0, len(got.Projects))
+	for _, p := range got.Projects {
+		projects = append(projects, p.Value())
+	}
+	if diff := cmp.Diff(want, projects); diff != "" {
+		t.Errorf("parseEntries() Projects mismatch (-want +got):\n%s", diff)
+	}
+
+	wantName := filepath.Base(root) + "/app@feature"
+	if w := got.Worktrees[types.NewString(inside)]; w.SessionName() != wantName {
+		t.Errorf("worktree session name = %q, want %q", w.SessionName(), wantName)
+	}
+}
+
+func TestConfigParser_ReadConfig_ParsesWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configFileAbs := filepath.Join(dir, ".tmux-sessionizer")
+	content := ConfigPrefix + "\n" +
+		WorkspacePrefix + "micro, /src/api, /src/web ,\n" +
+		WorkspacePrefix + "docs,/src/docs\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	want := map[string][]types.String{
+		"micro": {types.NewString("/src/api"), types.NewString("/src/web")},
+		"docs":  {types.NewString("/src/docs")},
+	}
+	if diff := cmp.Diff(want, got.Workspaces, cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})); diff != "" {
+		t.Errorf("ReadConfig() Workspaces mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigParser_ReadConfig_ParsesHooks(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
+	content := ConfigPrefix + "\n" +
+		HookPrefix + "post-create,docker compose up -d\n" +
+		HookPrefix + "post-create,git fetch --all, --prune\n" +
+		HookPrefix + "pre-kill,docker compose stop\n" +
+		HookTimeoutPrefix + "1m\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	want := map[hook.Event][]string{
+		hook.PostCreate: {"docker compose up -d", "git fetch --all, --prune"},
+		hook.PreKill:    {"docker compose stop"},
+	}
+	if diff := cmp.Diff(want, got.Hooks); diff != "" {
+		t.Errorf("ReadConfig() Hooks mismatch (-want +got):\n%s", diff)
+	}
+	if got.HookTimeout != time.Minute {
+		t.Errorf("ReadConfig() HookTimeout = %v, want %v", got.HookTimeout, time.Minute)
+	}
+}
+
+func TestConfigParser_ReadConfig_ReadsDiscoveryTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    time.Duration
+		wantErr error
+	}{
+		{
+			name:    "default",
+			content: ConfigPrefix + "\n",
+			want:    DefaultRootTimeout,
+		},
+		{
+			name:    "set",
+			content: ConfigPrefix + "\n" + DiscoveryTimeoutPrefix + " 10s\n",
+			want:    10 * time.Second,
+		},
+		{
+			name:    "not positive",
+			content: ConfigPrefix + "\n" + DiscoveryTimeoutPrefix + "0s\n",
+			wantErr: ErrInvalidTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
+			if err := os.WriteFile(configFileAbs, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.DiscoveryTimeout != tt.want {
+				t.Errorf("ReadConfig() DiscoveryTimeout = %v, want %v", got.DiscoveryTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigParser_ReadConfig_FallsBackToDefaultCloneRootWhenBlank(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
+	if err := os.WriteFile(configFileAbs, []byte(ConfigPrefix+"\n"+ClonePrefix+" \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	want, err := NewFiler().ExpandTildeAsHomeDir(DefaultCloneRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CloneRoot.Value() != want {
+		t.Errorf("ReadConfig() CloneRoot = %q, want %q", got.CloneRoot.Value(), want)
+	}
+}
+
+func TestConfigParser_ReadConfig_SkipsUnknownHookEvent(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
+	content := ConfigPrefix + "\n" + HookPrefix + "post-kill,true\n" + HookPrefix + "pre-kill,docker compose stop\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	if len(got.Skipped) != 1 || !errors.Is(got.Skipped[0], hook.ErrUnknownEvent) {
+		t.Errorf("ReadConfig() Skipped = %v, want a single hook.ErrUnknownEvent", got.Skipped)
+	}
+	if diff := cmp.Diff(map[hook.Event][]string{hook.PreKill: {"docker compose stop"}}, got.Hooks); diff != "" {
+		t.Errorf("ReadConfig() Hooks mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigParser_ReadConfig_ParsesRootDepthAndProjects(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, d := range []string{"deep/org/repo", "deep/solo", "single/nested"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFileAbs := filepath.Join(dir, ".tmux-sessionizer")
+	content := ConfigPrefix + "\n" +
+		RootPrefix + "2," + filepath.Join(dir, "deep") + "\n" +
+		ProjectPrefix + filepath.Join(dir, "single") + "," + filepath.Join(dir, "ghost") + "\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	wantRegistered := []types.String{types.NewString(filepath.Join(dir, "deep"))}
+	if diff := cmp.Diff(wantRegistered, got.Registered, opt); diff != "" {
+		t.Errorf("ReadConfig() Registered mismatch (-want +got):\n%s", diff)
+	}
+	// A registered project that is gone stays registered but is no candidate.
+	wantRegisteredProjects := []types.String{
+		types.NewString(filepath.Join(dir, "single")),
+		types.NewString(filepath.Join(dir, "ghost")),
+	}
+	if diff := cmp.Diff(wantRegisteredProjects, got.RegisteredProjects, opt); diff != "" {
+		t.Errorf("ReadConfig() RegisteredProjects mismatch (-want +got):\n%s", diff)
+	}
+	wantProjects := []types.String{
+		types.NewString(filepath.Join(dir, "deep/org")),
+		types.NewString(filepath.Join(dir, "deep/org/repo")),
+		types.NewString(filepath.Join(dir, "deep/solo")),
+		types.NewString(filepath.Join(dir, "single")),
+	}
+	if diff := cmp.Diff(wantProjects, got.Projects, opt); diff != "" {
+		t.Errorf("ReadConfig() Projects mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigParser_ReadConfig_RejectsInvalidDepth(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
+	if err := os.WriteFile(configFileAbs, []byte(ConfigPrefix+"\n"+RootPrefix+"0,/src\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs); !errors.Is(err, ErrInvalidDepth) {
+		t.Errorf("expected ErrInvalidDepth, got %v", err)
+	}
+}
+
+func TestConfigParser_parseEntries_SkipsRootsTakingLongerThanTimeout(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fast, hung := filepath.Join(dir, "fast"), filepath.Join(dir, "hung")
+	for _, d := range []string{filepath.Join(fast, "app"), filepath.Join(hung, "app")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	cp := NewConfigParser()
+	cp.rootTimeout = 50 * time.Millisecond
+	cp.readDir = func(name string) ([]os.DirEntry, error) {
+		if name == hung {
+			// A hung mount never answers while the test runs.
+			<-release
+		}
+		return os.ReadDir(name)
+	}
+
+	got := newConfig()
+	if err := cp.parseEntries(t.Context(), got, rootEntries(hung, fast), NewFiler()); err != nil {
+		t.Fatalf("parseEntries() error = %v", err)
+	}
+
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	if diff := cmp.Diff([]types.String{types.NewString(filepath.Join(fast, "app"))}, got.Projects, opt); diff != "" {
+		t.Errorf("parseEntries() Projects mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]types.String{types.NewString(hung)}, got.SlowRoots, opt); diff != "" {
+		t.Errorf("parseEntries() SlowRoots mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigParser_parseEntries_KeepsProjectsFoundBeforeStop(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fast, hung := filepath.Join(dir, "fast"), filepath.Join(dir, "hung")
+	for _, d := range []string{filepath.Join(fast, "app"), filepath.Join(hung, "app")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithCancelCause(t.Context())
+	defer cancel(nil)
+	cp := NewConfigParser()
+	cp.readDir = func(name string) ([]os.DirEntry, error) {
+		if name == hung {
+			<-release
+		}
+		return os.ReadDir(name)
+	}
+	// Picking the first project stops discovery long before hung times out.
+	cp.OnProject(func(_ *Config, _ types.String) {
+		cancel(ErrDiscoveryStopped)
+	})
+
+	got := newConfig()
+	if err := cp.parseEntries(ctx, got, rootEntries(fast, hung), NewFiler()); err != nil {
+		t.Fatalf("parseEntries() error = %v", err)
+	}
+
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	if diff := cmp.Diff([]types.String{types.NewString(filepath.Join(fast, "app"))}, got.Projects, opt); diff != "" {
+		t.Errorf("parseEntries() Projects mismatch (-want +got):\n%s", diff)
+	}
+	if len(got.SlowRoots) > 0 {
+		t.Errorf("parseEntries() SlowRoots = %v, want none", got.SlowRoots)
+	}
+}
+
+func TestConfigParser_parseEntries_SkipsUnreadableDirectories(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	root, locked := filepath.Join(dir, "root"), filepath.Join(dir, "locked")
+	for _, d := range []string{filepath.Join(root, "private", "app"), filepath.Join(root, "public", "app"), locked} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errDenied := errors.New("permission denied")
+	cp := NewConfigParser()
+	cp.readDir = func(name string) ([]os.DirEntry, error) {
+		if name == locked || name == filepath.Join(root, "private") {
+			return nil, errDenied
+		}
+		return os.ReadDir(name)
+	}
+
+	config := newConfig()
+	entries := []rootEntry{
+		{line: ConfigLine{Text: root}, depth: 2},
+		{line: ConfigLine{Text: locked}, depth: 1},
+	}
+	if err := cp.parseEntries(t.Context(), config, entries, NewFiler()); err != nil {
+		t.Fatalf("parseEntries() error = %v", err)
+	}
+
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	want := []types.String{
+		types.NewString(filepath.Join(root, "private")),
+		types.NewString(filepath.Join(root, "public")),
+		types.NewString(filepath.Join(root, "public", "app")),
+	}
+	if diff := cmp.Diff(want, config.Projects, opt); diff != "" {
+		t.Errorf("parseEntries() Projects mismatch (-want +got):\n%s", diff)
+	}
+	if len(config.Skipped) != 2 || !errors.Is(config.Skipped[0], errDenied) || !errors.Is(config.Skipped[1], errDenied) {
+		t.Errorf("parseEntries() Skipped = %v, want both unreadable directories", config.Skipped)
+	}
+}
+
+func TestConfigParser_parseEntries_StopsWhenCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if err := NewConfigParser().parseEntries(ctx, newConfig(), rootEntries(t.TempDir()), NewFiler()); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestConfigParser_ReadConfig_StreamsProjectsInOrder(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, d := range []string{"a/app", "a/tool", "b/blog"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFileAbs := filepath.Join(dir, ".tmux-sessionizer")
+	content := ConfigPrefix + filepath.Join(dir, "b") + "," + filepath.Join(dir, "a") + "\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	streamed := []types.String{}
+	got, err := NewConfigParser().OnProject(func(_ *Config, project types.String) {
+		streamed = append(streamed, project)
+	}).ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	want := []types.String{
+		types.NewString(filepath.Join(dir, "b/blog")),
+		types.NewString(filepath.Join(dir, "a/app")),
+		types.NewString(filepath.Join(dir, "a/tool")),
+	}
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	if diff := cmp.Diff(want, streamed, opt); diff != "" {
+		t.Errorf("OnProject() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want, got.Projects, opt); diff != "" {
+		t.Errorf("ReadConfig() Projects mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigParser_ReadConfig_ReportsRootsBeforeProjects(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	configFileAbs := filepath.Join(dir, ".tmux-sessionizer")
+	content := ConfigPrefix + dir + "\n" + LabelPrefix + "relative\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []string{}
+	_, err := NewConfigParser().OnRoots(func(config *Config) {
+		events = append(events, "roots "+config.LabelOf(filepath.Join(dir, "app")))
+	}).OnProject(func(_ *Config, project types.String) {
+		events = append(events, "project "+project.Value())
+	}).ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	want := []string{"roots app", "project " + filepath.Join(dir, "app")}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("ReadConfig() events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigParser_ReadConfig_SkipsEntryWithUndefinedVariable(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	configFileAbs := filepath.Join(dir, ".tmux-sessionizer")
+	content := ConfigPrefix + "$TMUX_SESSIONIZER_SURELY_UNDEFINED/src," + dir + "\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	if len(got.Skipped) != 1 || !errors.Is(got.Skipped[0], ErrUndefinedEnv) {
+		t.Errorf("ReadConfig() Skipped = %v, want a single ErrUndefinedEnv", got.Skipped)
+	}
+	if len(got.Projects) != 1 || got.Projects[0].Value() != filepath.Join(dir, "app") {
+		t.Errorf("ReadConfig() Projects = %v, want %s", got.Projects, filepath.Join(dir, "app"))
+	}
+}
@@ -0,0 +1,143 @@
+package io
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+const (
+	// EnvPrefix sets an environment variable for new sessions, written as
+	// env=KEY=VALUE for every session or env=<path>,KEY=VALUE for the sessions
+	// of the project at path or of the projects under it.
+	EnvPrefix = "env="
+	// EnvFile is read from the project directory last, so it wins over the config.
+	EnvFile = ".env"
+)
+
+var (
+	ErrInvalidEnv = errors.New("invalid environment variable")
+)
+
+// Env is a single environment variable. An empty Scope applies everywhere.
+type Env struct {
+	Scope types.String
+	Key   string
+	Value string
+}
+
+// Environ returns the KEY=VALUE pairs a new session for projectPath starts
+// with: variables for every session first, then those of enclosing scopes
+// from the outermost in, and the project's .env file last. A linked worktree
+// starts with everything its repository would, overridden by its own.
+func (c *Config) Environ(projectPath string) ([]string, error) {
+	envs, err := c.envOf(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if worktree, exists := c.Worktrees[types.NewString(projectPath)]; exists {
+		repoEnvs, err := c.envOf(worktree.Repository.Value())
+		if err != nil {
+			return nil, err
+		}
+		envs = append(repoEnvs, envs...)
+	}
+
+	keys, values := []string{}, make(map[string]string)
+	for _, e := range envs {
+		if _, exists := values[e.Key]; !exists {
+			keys = append(keys, e.Key)
+		}
+		values[e.Key] = e.Value
+	}
+
+	environ := make([]string, 0, len(keys))
+	for _, k := range keys {
+		environ = append(environ, k+"="+values[k])
+	}
+	return environ, nil
+}
+
+// envOf returns the variables that apply to projectPath, in the order they
+// override each other.
+func (c *Config) envOf(projectPath string) ([]Env, error) {
+	scoped := make([]Env, 0, len(c.Env))
+	for _, e := range c.Env {
+		scope := e.Scope.Value()
+		if len(scope) == 0 || projectPath == scope || strings.HasPrefix(projectPath, scope+string(filepath.Separator)) {
+			scoped = append(scoped, e)
+		}
+	}
+	// Stable, so variables of the same scope keep their config order.
+	slices.SortStableFunc(scoped, func(a, b Env) int {
+		return len(a.Scope.Value()) - len(b.Scope.Value())
+	})
+
+	fromFile, err := readEnvFile(filepath.Join(projectPath, EnvFile))
+	if err != nil {
+		return nil, err
+	}
+	return append(scoped, fromFile...), nil
+}
+
+// SplitEnv splits the value of an env= line into its raw scope, empty when
+// there is none, and the variable. A comma before the first '=' tells the
+// scoped form apart, since keys never hold one.
+func SplitEnv(raw string) (string, Env, error) {
+	scope, pair := "", raw
+	if comma := strings.Index(raw, ","); comma >= 0 && comma < strings.Index(raw, "=") {
+		scope, pair = raw[:comma], raw[comma+1:]
+	}
+
+	e, err := parseEnv(pair)
+	return scope, e, err
+}
+
+// parseEnv reads a KEY=VALUE pair, optionally prefixed with "export " and
+// with the value wrapped in matching quotes, as .env files usually have it.
+func parseEnv(raw string) (Env, error) {
+	key, value, found := strings.Cut(strings.TrimPrefix(strings.TrimSpace(raw), "export "), "=")
+	key = strings.TrimSpace(key)
+	if !found || len(key) == 0 || strings.ContainsAny(key, " \t") {
+		return Env{}, fmt.Errorf("%q:%w", raw, ErrInvalidEnv)
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return Env{Key: key, Value: value}, nil
+}
+
+// readEnvFile reads a .env file. A missing file is no error, most projects have none.
+func readEnvFile(path string) ([]Env, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	envs := []Env{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := parseEnv(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d:%w", path, lineNo, err)
+		}
+		envs = append(envs, e)
+	}
+	return envs, scanner.Err()
+}
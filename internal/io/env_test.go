@@ -0,0 +1,114 @@
+package io
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfig_Environ(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	project := filepath.Join(root, "api")
+	if err := os.Mkdir(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	envFile := "# local overrides\nexport GOFLAGS=-mod=vendor\nKUBECONFIG='~/.kube/api'\n"
+	if err := os.WriteFile(filepath.Join(project, EnvFile), []byte(envFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{Env: []Env{
+		{Scope: types.NewString(project), Key: "AWS_PROFILE", Value: "api"},
+		{Key: "AWS_PROFILE", Value: "default"},
+		{Key: "GOFLAGS", Value: "-mod=mod"},
+		{Scope: types.NewString(root), Key: "AWS_PROFILE", Value: "work"},
+		{Scope: types.NewString(root + "-other"), Key: "KUBECONFIG", Value: "other"},
+	}}
+
+	got, err := config.Environ(project)
+	if err != nil {
+		t.Fatalf("Environ() error = %v", err)
+	}
+
+	want := []string{"AWS_PROFILE=api", "GOFLAGS=-mod=vendor", "KUBECONFIG=~/.kube/api"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Environ() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfig_Environ_StartsWorktreeLikeItsRepository(t *testing.T) {
+	t.Parallel()
+
+	repo, worktree := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, EnvFile), []byte("DATABASE_URL=local\nPORT=3000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, EnvFile), []byte("PORT=3001\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		Env: []Env{{Scope: types.NewString(repo), Key: "AWS_PROFILE", Value: "api"}},
+		Worktrees: map[types.String]git.Worktree{
+			types.NewString(worktree): {Repository: types.NewString(repo), Path: types.NewString(worktree)},
+		},
+	}
+
+	got, err := config.Environ(worktree)
+	if err != nil {
+		t.Fatalf("Environ() error = %v", err)
+	}
+
+	want := []string{"AWS_PROFILE=api", "DATABASE_URL=local", "PORT=3001"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Environ() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfig_Environ_ReportsInvalidEnvFileLine(t *testing.T) {
+	t.Parallel()
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, EnvFile), []byte("OK=1\nnot a variable\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := (&Config{}).Environ(project)
+	if !errors.Is(err, ErrInvalidEnv) {
+		t.Errorf("expected ErrInvalidEnv, got %v", err)
+	}
+}
+
+func TestConfigParser_ReadConfig_ParsesEnv(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := filepath.Join(t.TempDir(), ".tmux-sessionizer")
+	content := ConfigPrefix + "\n" +
+		EnvPrefix + "GOFLAGS=-tags=a,b\n" +
+		EnvPrefix + "/src/api,AWS_PROFILE=api\n"
+	if err := os.WriteFile(configFileAbs, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), configFileAbs)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	want := []Env{
+		{Key: "GOFLAGS", Value: "-tags=a,b"},
+		{Scope: types.NewString("/src/api"), Key: "AWS_PROFILE", Value: "api"},
+	}
+	if diff := cmp.Diff(want, got.Env, cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})); diff != "" {
+		t.Errorf("ReadConfig() Env mismatch (-want +got):\n%s", diff)
+	}
+}
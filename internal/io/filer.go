@@ -9,6 +9,10 @@ import (
 	"strings"
 )
 
+var (
+	ErrUndefinedEnv = errors.New("undefined environment variable")
+)
+
 type Filer struct {
 }
 
@@ -31,6 +35,63 @@ func (fl *Filer) ExpandTildeAsHomeDir(path string) (string, error) {
 	return filepath.Join(userHome, trimmed), nil
 }
 
+// ExpandEnv replaces $VAR and ${VAR} in path like a shell does, except that
+// an undefined variable is an error rather than an empty string, which would
+// silently turn $WORK/repos into /repos.
+func (fl *Filer) ExpandEnv(path string) (string, error) {
+	undefined := []string{}
+	expanded := os.Expand(path, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			undefined = append(undefined, "$"+name)
+		}
+		return value
+	})
+
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("%s in %s:%w", strings.Join(undefined, ", "), path, ErrUndefinedEnv)
+	}
+	return expanded, nil
+}
+
+// ResolveRoots turns a registered entry into the absolute root directories
+// it names: environment variables and a leading ~ are expanded, then a glob
+// yields every matching directory in lexical order. A plain path is returned
+// even if it does not exist.
+func (fl *Filer) ResolveRoots(entry string) ([]string, error) {
+	path, err := fl.ExpandEnv(entry)
+	if err != nil {
+		return nil, err
+	}
+	if path, err = fl.ExpandTildeAsHomeDir(path); err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsGlob(absPath) {
+		return []string{absPath}, nil
+	}
+
+	matches, err := filepath.Glob(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q:%w", entry, err)
+	}
+	roots := []string{}
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			roots = append(roots, m)
+		}
+	}
+	return roots, nil
+}
+
+// IsGlob tells whether path holds shell glob metacharacters.
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
 // Exists reports why the path cannot be used: missing or unreadable.
 // Stat is enough here; opening the file would consume a descriptor for nothing.
 func (fl *Filer) Exists(path string) error {
@@ -2,6 +2,7 @@ package io
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -69,3 +70,62 @@ func TestFiler_Exists_ReturnsErrorForMissingPath(t *testing.T) {
 		t.Error("expected error for missing path, got nil")
 	}
 }
+
+func TestFiler_ExpandEnv(t *testing.T) {
+	t.Parallel()
+
+	home := os.Getenv("HOME")
+	if len(home) == 0 {
+		t.Skip("HOME is not set")
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr error
+	}{
+		{name: "plain", path: "$HOME/src", want: home + "/src"},
+		{name: "braced", path: "${HOME}/repos", want: home + "/repos"},
+		{name: "no variable", path: "~/src", want: "~/src"},
+		{name: "undefined", path: "$TMUX_SESSIONIZER_SURELY_UNDEFINED/repos", wantErr: ErrUndefinedEnv},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewFiler().ExpandEnv(tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExpandEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFiler_ResolveRoots_ExpandsGlobToDirectories(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, d := range []string{"b/repos", "a/repos", "c"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewFiler().ResolveRoots(filepath.Join(dir, "*", "repos"))
+	if err != nil {
+		t.Fatalf("ResolveRoots() error = %v", err)
+	}
+
+	want := []string{filepath.Join(dir, "a", "repos"), filepath.Join(dir, "b", "repos")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ResolveRoots() mismatch (-want +got):\n%s", diff)
+	}
+}
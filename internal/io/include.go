@@ -0,0 +1,176 @@
+package io
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// IncludePrefix reads other config files in place of the line, written as
+	// include=<path>,<path>... or include = [<path>, <path>]. Paths may hold
+	// globs and a leading ~; relative ones are relative to the including file.
+	IncludePrefix = "include="
+)
+
+var (
+	ErrIncludeCycle = errors.New("config files include each other")
+)
+
+// ConfigLine is a line of the config file, or of a file it includes, with
+// the place it came from so errors can point there.
+type ConfigLine struct {
+	File string
+	Line int
+	Text string
+}
+
+// wrap prefixes err with the place of the line, when it has one.
+func (l ConfigLine) wrap(err error) error {
+	if len(l.File) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s:%d:%w", l.File, l.Line, err)
+}
+
+// withText keeps the place of the line for a part of it, e.g. a value.
+func (l ConfigLine) withText(text string) ConfigLine {
+	l.Text = text
+	return l
+}
+
+// OverlayFile is the per-host overlay of configFileAbs: the same file name
+// with the short hostname appended, e.g. config.laptop. It is read after the
+// config file, so its scalar settings win and its lists come last.
+func OverlayFile(configFileAbs string) (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	host, _, _ = strings.Cut(host, ".")
+	return configFileAbs + "." + host, nil
+}
+
+// ReadConfigLines reads configFileAbs with every include resolved in place,
+// followed by its per-host overlay if there is one. Globs are expanded in
+// lexical order, and a file is read only once even if included again, so the
+// result does not depend on anything but the files.
+func ReadConfigLines(filer *Filer, configFileAbs string) ([]ConfigLine, error) {
+	r := &includeReader{filer: filer, read: make(map[string]struct{})}
+	if err := r.readFile(configFileAbs, nil); err != nil {
+		return nil, err
+	}
+
+	overlay, err := OverlayFile(configFileAbs)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(overlay); err == nil {
+		if err := r.readFile(overlay, nil); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return r.lines, nil
+}
+
+type includeReader struct {
+	filer *Filer
+	lines []ConfigLine
+	read  map[string]struct{}
+}
+
+// readFile appends the lines of path. stack holds the files being read, each
+// including the next one, to tell a cycle apart from a file included twice.
+func (r *includeReader) readFile(path string, stack []string) error {
+	if slices.Contains(stack, path) {
+		return fmt.Errorf("%s:%w", strings.Join(append(stack, path), " -> "), ErrIncludeCycle)
+	}
+	if _, read := r.read[path]; read {
+		return nil
+	}
+	r.read[path] = struct{}{}
+	stack = append(stack, path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := ConfigLine{File: path, Line: lineNo, Text: scanner.Text()}
+		patterns, isInclude := parseInclude(line.Text)
+		if !isInclude {
+			r.lines = append(r.lines, line)
+			continue
+		}
+
+		for _, pattern := range patterns {
+			files, err := r.resolve(filepath.Dir(path), pattern)
+			if err != nil {
+				return line.wrap(err)
+			}
+			for _, file := range files {
+				if err := r.readFile(file, stack); err != nil {
+					return line.wrap(err)
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// resolve turns an include pattern into the files it names. A plain path
+// must exist, while a glob may match nothing.
+func (r *includeReader) resolve(dir string, pattern string) ([]string, error) {
+	pattern, err := r.filer.ExpandTildeAsHomeDir(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		if err := r.filer.Exists(pattern); err != nil {
+			return nil, fmt.Errorf("failed to include:%w", err)
+		}
+		return []string{pattern}, nil
+	}
+
+	// Glob returns the matches in lexical order.
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include glob %q:%w", pattern, err)
+	}
+	return files, nil
+}
+
+// parseInclude reads an include line, tolerating spaces around '=' and a
+// list wrapped in brackets.
+func parseInclude(line string) ([]string, bool) {
+	key, value, found := strings.Cut(line, "=")
+	if !found || strings.TrimSpace(key)+"=" != IncludePrefix {
+		return nil, false
+	}
+
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	patterns := []string{}
+	for _, p := range strings.Split(value, ",") {
+		// Quotes are allowed too, as in TOML-like lists.
+		if p = strings.Trim(strings.TrimSpace(p), `"'`); len(p) > 0 {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, true
+}
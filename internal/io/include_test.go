@@ -0,0 +1,91 @@
+package io
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadConfigLines_ResolvesIncludesAndOverlay(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config")
+	overlay, err := OverlayFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"config":               "default=/src\ninclude = [base.conf, hosts/*.conf]\nclone=/ghq\n",
+		"base.conf":            "default=/base\n",
+		"hosts/b.conf":         "default=/b\ninclude=../base.conf\n",
+		"hosts/a.conf":         "default=/a\n",
+		filepath.Base(overlay): "worktree=/wt\n",
+	})
+
+	got, err := ReadConfigLines(NewFiler(), config)
+	if err != nil {
+		t.Fatalf("ReadConfigLines() error = %v", err)
+	}
+
+	want := []ConfigLine{
+		{File: config, Line: 1, Text: "default=/src"},
+		{File: filepath.Join(dir, "base.conf"), Line: 1, Text: "default=/base"},
+		{File: filepath.Join(dir, "hosts", "a.conf"), Line: 1, Text: "default=/a"},
+		{File: filepath.Join(dir, "hosts", "b.conf"), Line: 1, Text: "default=/b"},
+		{File: config, Line: 3, Text: "clone=/ghq"},
+		{File: overlay, Line: 1, Text: "worktree=/wt"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadConfigLines() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadConfigLines_DetectsCycle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config": "default=\ninclude=a.conf\n",
+		"a.conf": "include=b.conf\n",
+		"b.conf": "include=a.conf\n",
+	})
+
+	_, err := ReadConfigLines(NewFiler(), filepath.Join(dir, "config"))
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Errorf("expected ErrIncludeCycle, got %v", err)
+	}
+}
+
+func TestConfigParser_ReadConfig_NamesFileOfBadEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config":    "default=\ninclude=team.conf\n",
+		"team.conf": "env=FOO=bar\nhook-timeout=soon\n",
+	})
+
+	_, err := NewConfigParser().ReadConfig(t.Context(), NewFiler(), filepath.Join(dir, "config"))
+	if err == nil || !strings.HasPrefix(err.Error(), filepath.Join(dir, "team.conf")+":2:") {
+		t.Errorf("expected error pointing at team.conf:2, got %v", err)
+	}
+}
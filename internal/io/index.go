@@ -0,0 +1,40 @@
+package io
+
+import (
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/git"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+)
+
+// ProjectIndex is what discovery found for a config file, kept between runs
+// by the daemon so the roots need not be walked at all.
+type ProjectIndex struct {
+	Projects  []types.String
+	Worktrees map[types.String]git.Worktree
+	SlowRoots []types.String
+}
+
+// IndexOf takes the discovery results out of config.
+func IndexOf(config *Config) *ProjectIndex {
+	return &ProjectIndex{
+		Projects:  config.Projects,
+		Worktrees: config.Worktrees,
+		SlowRoots: config.SlowRoots,
+	}
+}
+
+// WithIndex makes ReadConfig take the projects from index instead of walking
+// the roots. Everything else is still read from the config file.
+func (c *ConfigParser) WithIndex(index *ProjectIndex) *ConfigParser {
+	c.index = index
+	return c
+}
+
+func (c *ConfigParser) applyIndex(config *Config) {
+	for path, worktree := range c.index.Worktrees {
+		config.Worktrees[path] = worktree
+	}
+	config.SlowRoots = append(config.SlowRoots, c.index.SlowRoots...)
+	for _, project := range c.index.Projects {
+		c.createProjects(config, project.Value())
+	}
+}
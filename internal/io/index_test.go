@@ -0,0 +1,50 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigParser_parseEntries_TakesProjectsFromIndex(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := cmp.Comparer(func(a, b types.String) bool {
+		return a.Value() == b.Value()
+	})
+	walked := newConfig()
+	if err := NewConfigParser().parseEntries(t.Context(), walked, rootEntries(root), NewFiler()); err != nil {
+		t.Fatalf("parseEntries() error = %v", err)
+	}
+	if diff := cmp.Diff([]types.String{types.NewString(root)}, walked.ListedDirs, opt); diff != "" {
+		t.Errorf("ListedDirs mismatch (-want +got):\n%s", diff)
+	}
+
+	// The index is trusted as is, even for a project gone since.
+	index := IndexOf(walked)
+	index.Projects = append(index.Projects, types.NewString(filepath.Join(root, "gone")))
+	cp := NewConfigParser().WithIndex(index)
+	cp.readDir = func(name string) ([]os.DirEntry, error) {
+		t.Errorf("readDir(%q) called, want the roots not to be walked", name)
+		return nil, nil
+	}
+	config := newConfig()
+	if err := cp.parseEntries(t.Context(), config, rootEntries(root), NewFiler()); err != nil {
+		t.Fatalf("parseEntries() error = %v", err)
+	}
+	want := []types.String{types.NewString(filepath.Join(root, "app")), types.NewString(filepath.Join(root, "gone"))}
+	if diff := cmp.Diff(want, config.Projects, opt); diff != "" {
+		t.Errorf("Projects mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]types.String{types.NewString(root)}, config.Registered, opt); diff != "" {
+		t.Errorf("Registered mismatch (-want +got):\n%s", diff)
+	}
+}
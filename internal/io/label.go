@@ -0,0 +1,135 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// LabelPrefix sets how the picker shows projects, written as
+	// label=<style>[,color][,running].
+	LabelPrefix = "label="
+)
+
+// LabelStyle is how a project path is shortened in the picker.
+type LabelStyle string
+
+const (
+	// LabelPath shows the absolute path, which is the default.
+	LabelPath LabelStyle = "path"
+	// LabelHome shows the path with the home directory as ~.
+	LabelHome LabelStyle = "home"
+	// LabelRelative shows the path relative to the root it was found under.
+	LabelRelative LabelStyle = "relative"
+	// LabelRoot shows the name of the root followed by the relative path.
+	LabelRoot LabelStyle = "root"
+)
+
+const (
+	labelColor = "color"
+	// labelRunning used to mark projects with a session. The picker lists
+	// running sessions tagged of their own now, so it is accepted and ignored.
+	labelRunning = "running"
+
+	ansiFaint = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+var (
+	ErrInvalidLabel = errors.New("invalid label")
+)
+
+// LabelStyles lists every style in the order they are documented.
+var LabelStyles = []LabelStyle{LabelPath, LabelHome, LabelRelative, LabelRoot}
+
+// Label is how the picker shows a project. The project path itself is always
+// kept next to the label, so picking a label still gives the exact path.
+type Label struct {
+	Style LabelStyle
+	// Color dims the directories leading to the project name.
+	Color bool
+	// home is the home directory LabelHome and projects outside any root are
+	// shortened with.
+	home string
+}
+
+// ParseLabel reads the value of a label= line.
+func ParseLabel(raw string) (Label, error) {
+	fields := strings.Split(raw, ",")
+	label := Label{Style: LabelStyle(strings.TrimSpace(fields[0]))}
+	if !slices.Contains(LabelStyles, label.Style) {
+		return Label{}, fmt.Errorf("style %q, want one of %v:%w", label.Style, LabelStyles, ErrInvalidLabel)
+	}
+	for _, f := range fields[1:] {
+		switch strings.TrimSpace(f) {
+		case labelColor:
+			label.Color = true
+		case labelRunning:
+		default:
+			return Label{}, fmt.Errorf("option %q, want %s or %s:%w", strings.TrimSpace(f), labelColor, labelRunning, ErrInvalidLabel)
+		}
+	}
+	return label, nil
+}
+
+// LabelOf returns the label of the project at projectPath.
+func (c *Config) LabelOf(projectPath string) string {
+	dir, name := c.shorten(projectPath)
+	if c.Label.Color && len(dir) > 0 {
+		dir = ansiFaint + dir + ansiReset
+	}
+	return dir + name
+}
+
+// shorten splits the label of projectPath into the directories leading to
+// the project, ending with a separator, and the project name.
+func (c *Config) shorten(projectPath string) (string, string) {
+	short := projectPath
+	switch c.Label.Style {
+	case LabelHome:
+		short = c.homeRelative(projectPath)
+	case LabelRelative, LabelRoot:
+		root, found := c.RootOf(projectPath)
+		if !found {
+			// project= entries and workspaces have no root to be relative to.
+			short = c.homeRelative(projectPath)
+			break
+		}
+		short, _ = filepath.Rel(root, projectPath)
+		if c.Label.Style == LabelRoot {
+			short = filepath.Join(filepath.Base(root), short)
+		}
+	}
+
+	i := strings.LastIndex(short, string(filepath.Separator)) + 1
+	return short[:i], short[i:]
+}
+
+// RootOf returns the innermost registered root projectPath lies under.
+func (c *Config) RootOf(projectPath string) (string, bool) {
+	found := ""
+	for _, r := range c.Registered {
+		root := r.Value()
+		if strings.HasPrefix(projectPath, root+string(filepath.Separator)) && len(root) > len(found) {
+			found = root
+		}
+	}
+	return found, len(found) > 0
+}
+
+func (c *Config) homeRelative(projectPath string) string {
+	home := c.Label.home
+	if len(home) == 0 {
+		return projectPath
+	}
+	if projectPath == home {
+		return "~"
+	}
+	if rel, found := strings.CutPrefix(projectPath, home+string(filepath.Separator)); found {
+		return filepath.Join("~", rel)
+	}
+	return projectPath
+}
@@ -0,0 +1,122 @@
+package io
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLabel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    Label
+		wantErr error
+	}{
+		{
+			name: "style only",
+			raw:  "home",
+			want: Label{Style: LabelHome},
+		},
+		{
+			name: "style with options",
+			raw:  "root, running,color",
+			want: Label{Style: LabelRoot, Color: true},
+		},
+		{
+			name:    "unknown style",
+			raw:     "short",
+			wantErr: ErrInvalidLabel,
+		},
+		{
+			name:    "unknown option",
+			raw:     "path,bold",
+			wantErr: ErrInvalidLabel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseLabel(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseLabel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(Label{})); diff != "" {
+				t.Errorf("ParseLabel() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfig_LabelOf(t *testing.T) {
+	t.Parallel()
+
+	registered := []types.String{types.NewString("/home/me/src"), types.NewString("/home/me/src/github.com")}
+	tests := []struct {
+		name    string
+		label   Label
+		project string
+		want    string
+	}{
+		{
+			name:    "path",
+			label:   Label{Style: LabelPath},
+			project: "/home/me/src/app",
+			want:    "/home/me/src/app",
+		},
+		{
+			name:    "home",
+			label:   Label{Style: LabelHome},
+			project: "/home/me/src/app",
+			want:    "~/src/app",
+		},
+		{
+			name:    "relative to the innermost root",
+			label:   Label{Style: LabelRelative},
+			project: "/home/me/src/github.com/owner/app",
+			want:    "owner/app",
+		},
+		{
+			name:    "root name",
+			label:   Label{Style: LabelRoot},
+			project: "/home/me/src/app",
+			want:    "src/app",
+		},
+		{
+			name:    "project outside any root",
+			label:   Label{Style: LabelRoot},
+			project: "/home/me/dotfiles",
+			want:    "~/dotfiles",
+		},
+		{
+			name:    "outside home",
+			label:   Label{Style: LabelHome},
+			project: "/opt/app",
+			want:    "/opt/app",
+		},
+		{
+			name:    "color",
+			label:   Label{Style: LabelRelative, Color: true},
+			project: "/home/me/src/github.com/owner/app",
+			want:    "\x1b[2mowner/\x1b[0mapp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.label.home = "/home/me"
+			config := &Config{Registered: registered, Label: tt.label}
+			if got := config.LabelOf(tt.project); got != tt.want {
+				t.Errorf("LabelOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,29 @@ package session
 
 import "github.com/TlexCypher/my-tmux-sessionizer/internal/types"
 
+const (
+	// workspacePrefix starts the key of a workspace session, which never
+	// collides with a project path since those are absolute.
+	workspacePrefix = "workspace:"
+	// foreignPrefix starts the key of a foreign session, likewise.
+	foreignPrefix = "session:"
+)
+
 type Session struct {
 	Name        types.String
 	ProjectPath types.String
+	// Scratch marks a throwaway session whose directory is removed with it.
+	Scratch bool
+	// Attached tells whether any client is attached to the session.
+	Attached bool
+	// Workspace names the workspace a session with one window per project
+	// was opened for.
+	Workspace string
+	// Environ holds the KEY=VALUE pairs the session is created with.
+	Environ []string
+	// Foreign marks a session tmux-sessionizer did not create, e.g. with
+	// `tmux new`, which belongs to no project even in a project directory.
+	Foreign bool
 }
 
 func NewSession(name types.String, projectPath types.String) *Session {
@@ -17,3 +37,23 @@ func NewSession(name types.String, projectPath types.String) *Session {
 func (s *Session) Project() types.String {
 	return s.ProjectPath
 }
+
+// Key is what the session is known by: its project path, or for a workspace
+// session, which starts in the directory of its first project, the key of
+// the workspace, so the project can still have a session of its own. A
+// foreign session is known by its name, so several of them in one directory
+// stay apart.
+func (s *Session) Key() types.String {
+	if len(s.Workspace) > 0 {
+		return types.NewString(WorkspaceKey(s.Workspace))
+	}
+	if s.Foreign {
+		return types.NewString(foreignPrefix + s.Name.Value())
+	}
+	return s.ProjectPath
+}
+
+// WorkspaceKey is the key of the session of the workspace name.
+func WorkspaceKey(name string) string {
+	return workspacePrefix + name
+}
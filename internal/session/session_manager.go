@@ -2,37 +2,117 @@ package session
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
 )
 
 var (
-	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrInvalidSessionName = errors.New("invalid session name")
+	ErrDuplicatedSession  = errors.New("session name is already in use")
 )
 
 type SessionManager struct {
 	sessions               map[types.String]*Session
 	sessionNameTransformer *Transformer
+	// aliases maps a project path to the session name the user renamed it to.
+	aliases map[types.String]types.String
 }
 
 func NewSessionManager(sessions map[types.String]*Session, transformer *Transformer) *SessionManager {
 	return &SessionManager{
 		sessions:               sessions,
 		sessionNameTransformer: transformer,
+		aliases:                make(map[types.String]types.String),
 	}
 }
 
-func (sm *SessionManager) CreateSession(rawName string, rawPath string) *Session {
-	transformed := sm.sessionNameTransformer.Transform(rawPath)
+func (sm *SessionManager) WithAliases(aliases map[types.String]types.String) *SessionManager {
+	for path, name := range aliases {
+		sm.aliases[path] = name
+	}
 
-	sessionName, projectPath := types.NewString(transformed), types.NewString(rawPath)
+	return sm
+}
+
+func (sm *SessionManager) CreateSession(rawName string, rawPath string) *Session {
+	projectPath := types.NewString(rawPath)
 	if _, exists := sm.sessions[projectPath]; !exists {
-		sm.sessions[projectPath] = NewSession(sessionName, projectPath)
+		sm.sessions[projectPath] = NewSession(sm.NameOf(rawName, rawPath), projectPath)
 	}
 
 	return sm.sessions[projectPath]
 }
 
+// NameOf returns the name CreateSession gives a new session of rawPath: the
+// alias of the project if it has one, rawName made acceptable to tmux otherwise.
+func (sm *SessionManager) NameOf(rawName string, rawPath string) types.String {
+	if alias, exists := sm.aliases[types.NewString(rawPath)]; exists {
+		return alias
+	}
+
+	return types.NewString(sm.sessionNameTransformer.Transform(rawName))
+}
+
+// CreateScratchSession creates a session like CreateSession does, marked as
+// scratch so its directory is removed when the session is deleted.
+func (sm *SessionManager) CreateScratchSession(rawName string, rawPath string) *Session {
+	session := sm.CreateSession(rawName, rawPath)
+	session.Scratch = true
+
+	return session
+}
+
+// CreateWorkspaceSession creates a session like CreateSession does, known by
+// the key of the workspace name, that starts in rawPath.
+func (sm *SessionManager) CreateWorkspaceSession(name string, rawPath string) *Session {
+	session := sm.CreateSession(name, WorkspaceKey(name))
+	session.Workspace = name
+	session.ProjectPath = types.NewString(rawPath)
+
+	return session
+}
+
+// SessionName turns user input into a name tmux accepts, the same way
+// project paths are turned into session names.
+func (sm *SessionManager) SessionName(rawName string) (types.String, error) {
+	name := types.NewString(sm.sessionNameTransformer.Transform(rawName))
+	if len(name.Value()) == 0 || strings.Contains(name.Value(), ",") {
+		return types.String{}, fmt.Errorf("%q:%w", rawName, ErrInvalidSessionName)
+	}
+
+	return name, nil
+}
+
+// RenameSession gives the session of rawPath a new name and remembers it as
+// the alias of the project, so CreateSession reuses it later.
+func (sm *SessionManager) RenameSession(rawPath string, name types.String) (*Session, error) {
+	session, err := sm.GetSession(rawPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range sm.sessions {
+		if other != session && other.Name.Value() == name.Value() {
+			return nil, fmt.Errorf("%s:%w", name.Value(), ErrDuplicatedSession)
+		}
+	}
+
+	key := session.Key()
+	session.Name = name
+	if session.Foreign {
+		// It is known by its name, and there is no project to remember it for.
+		delete(sm.sessions, key)
+		sm.sessions[session.Key()] = session
+		return session, nil
+	}
+	sm.aliases[key] = name
+
+	return session, nil
+}
+
 func (sm *SessionManager) ListSessions() (sessions []*Session) {
 	for _, v := range sm.sessions {
 		sessions = append(sessions, v)
@@ -47,8 +127,8 @@ func (sm *SessionManager) FilterSessions(rawPaths []string) (sessions []*Session
 		paths[rp] = struct{}{}
 	}
 
-	for _, v := range sm.sessions {
-		if _, exists := paths[v.ProjectPath.Value()]; exists {
+	for key, v := range sm.sessions {
+		if _, exists := paths[key.Value()]; exists {
 			sessions = append(sessions, v)
 		}
 	}
@@ -65,6 +145,23 @@ func (sm *SessionManager) GetSession(rawPath string) (*Session, error) {
 	return nil, ErrSessionNotFound
 }
 
+// FindSession looks a session up by its project path first and falls back
+// to its session name, so users can refer to a session either way.
+func (sm *SessionManager) FindSession(raw string) (*Session, error) {
+	if session, err := sm.GetSession(raw); err == nil {
+		return session, nil
+	}
+
+	name := types.NewString(raw)
+	for _, session := range sm.sessions {
+		if session.Name.Value() == name.Value() {
+			return session, nil
+		}
+	}
+
+	return nil, ErrSessionNotFound
+}
+
 // DeleteSessions removes every session identified by rawPaths. Deletion runs
 // sequentially because the underlying map is not safe for concurrent writes.
 func (sm *SessionManager) DeleteSessions(rawPaths []string) error {
@@ -272,3 +272,151 @@ func TestSessionManager_ListSessions(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionManager_RenameSession(t *testing.T) {
+	t.Parallel()
+
+	const (
+		path1 = "/path/to/project1"
+		path2 = "/path/to/project2"
+	)
+
+	newManager := func() *SessionManager {
+		transformer := NewTransformer().WithRule(
+			NewTransformRule(
+				func(in string) string { return strings.ReplaceAll(in, ".", "_") },
+				func(in string) string { return strings.ReplaceAll(in, "_", ".") },
+			),
+		)
+
+		return NewSessionManager(map[types.String]*Session{
+			types.NewString(path1): {Name: types.NewString("project1"), ProjectPath: types.NewString(path1)},
+			types.NewString(path2): {Name: types.NewString("project2"), ProjectPath: types.NewString(path2)},
+		}, transformer)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		rawName  string
+		wantName string
+		wantErr  error
+	}{
+		{
+			name:     "rename keeps the project path",
+			path:     path1,
+			rawName:  "api",
+			wantName: "api",
+			wantErr:  nil,
+		},
+		{
+			name:     "new name is transformed like a project path",
+			path:     path1,
+			rawName:  "api.v2",
+			wantName: "api_v2",
+			wantErr:  nil,
+		},
+		{
+			name:    "name used by another session is rejected",
+			path:    path1,
+			rawName: "project2",
+			wantErr: ErrDuplicatedSession,
+		},
+		{
+			name:    "unknown session returns ErrSessionNotFound",
+			path:    "/path/to/nonexistent",
+			rawName: "api",
+			wantErr: ErrSessionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sm := newManager()
+
+			name, err := sm.SessionName(tt.rawName)
+			if err != nil {
+				t.Fatalf("SessionName() error = %v", err)
+			}
+
+			got, err := sm.RenameSession(tt.path, name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenameSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Name.Value() != tt.wantName || got.ProjectPath.Value() != tt.path {
+				t.Errorf("RenameSession() = %s:%s, want %s:%s", got.Name.Value(), got.ProjectPath.Value(), tt.wantName, tt.path)
+			}
+
+			found, err := sm.FindSession(tt.wantName)
+			if err != nil || found != got {
+				t.Errorf("FindSession(%q) = %v, %v, want renamed session", tt.wantName, found, err)
+			}
+		})
+	}
+}
+
+func TestSessionManager_CreateSession_UsesAlias(t *testing.T) {
+	t.Parallel()
+
+	sm := NewSessionManager(make(map[types.String]*Session), NewTransformer()).WithAliases(map[types.String]types.String{
+		types.NewString("/path/to/project1"): types.NewString("api"),
+	})
+
+	got := sm.CreateSession(project1RawInput, project1RawInput)
+
+	if got.Name.Value() != "api" {
+		t.Errorf("CreateSession() name = %q, want %q", got.Name.Value(), "api")
+	}
+}
+
+func TestSessionManager_CreateWorkspaceSession_KeepsProjectSessionApart(t *testing.T) {
+	t.Parallel()
+
+	sm := NewSessionManager(make(map[types.String]*Session), NewTransformer())
+	project := sm.CreateSession("/src/app", "/src/app")
+	workspace := sm.CreateWorkspaceSession("micro", "/src/app")
+
+	if project == workspace {
+		t.Fatal("expected the workspace session to be apart from the project session")
+	}
+	if got, err := sm.GetSession(WorkspaceKey("micro")); err != nil || got != workspace {
+		t.Errorf("GetSession(%q) = %v, %v, want the workspace session", WorkspaceKey("micro"), got, err)
+	}
+	if got, err := sm.GetSession("/src/app"); err != nil || got != project {
+		t.Errorf("GetSession(%q) = %v, %v, want the project session", "/src/app", got, err)
+	}
+	if got := workspace.ProjectPath.Value(); got != "/src/app" {
+		t.Errorf("workspace ProjectPath = %q, want %q", got, "/src/app")
+	}
+	if got := len(sm.ListSessions()); got != 2 {
+		t.Errorf("ListSessions() has %d sessions, want 2", got)
+	}
+}
+
+func TestSessionManager_RenameSession_RekeysForeignSession(t *testing.T) {
+	t.Parallel()
+
+	foreign := &Session{Name: types.NewString("notes"), ProjectPath: types.NewString("/src/app"), Foreign: true}
+	sm := NewSessionManager(map[types.String]*Session{types.NewString("session:notes"): foreign}, NewTransformer())
+
+	got, err := sm.RenameSession("session:notes", types.NewString("todo"))
+	if err != nil {
+		t.Fatalf("RenameSession() error = %v", err)
+	}
+	if found, err := sm.GetSession("session:todo"); err != nil || found != got {
+		t.Errorf("GetSession(%q) = %v, %v, want the renamed session", "session:todo", found, err)
+	}
+	if _, err := sm.GetSession("session:notes"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("GetSession(%q) error = %v, want %v", "session:notes", err, ErrSessionNotFound)
+	}
+	// A foreign session belongs to no project, so no alias is remembered.
+	if got := sm.CreateSession("/src/app", "/src/app"); got.Name.Value() != "/src/app" {
+		t.Errorf("CreateSession() name = %q, want %q", got.Name.Value(), "/src/app")
+	}
+}
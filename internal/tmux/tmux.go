@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/command"
@@ -13,6 +14,13 @@ import (
 
 const (
 	tmux = "TMUX"
+	// sessionizerOption is the user option marking every session created by
+	// tmux-sessionizer, while the others are foreign.
+	sessionizerOption = "@tmux-sessionizer"
+	// scratchOption is the user option marking a scratch session.
+	scratchOption = "@tmux-sessionizer-scratch"
+	// workspaceOption is the user option naming the workspace of a session.
+	workspaceOption = "@tmux-sessionizer-workspace"
 )
 
 type Tmux struct{}
@@ -22,30 +30,74 @@ func NewTmux() *Tmux {
 }
 
 func (t *Tmux) GatherExistingSessions(ctx context.Context) (map[types.String]*session.Session, error) {
-	tmuxCmd := command.NewTmuxCommand(ctx, "list-sessions", "-F", "#{session_name}:#{session_path}")
+	// Session names never contain ':' (see the session name transformer), so
+	// only the workspace name and the path may hold one. tmux prints control
+	// characters such as a tab as '_', so rather than after a separator, the
+	// workspace name comes after its length.
+	format := "#{session_name}:#{" + sessionizerOption + "}:#{" + scratchOption + "}:#{session_attached}:#{n:" + workspaceOption + "}:#{" + workspaceOption + "}:#{session_path}"
+	tmuxCmd := command.NewTmuxCommand(ctx, "list-sessions", "-F", format)
 
 	err := tmuxCmd.Run()
 	if err != nil {
-		return nil, fmt.Errorf("failed to gather existing tmux sessions with `tmux list-sessions -F '#{session_name}:#{session_path}'`: %w", err)
+		return nil, fmt.Errorf("failed to gather existing tmux sessions with `tmux list-sessions -F '%s'`: %w", format, err)
 	}
 
 	existingSessions := make(map[types.String]*session.Session, 0)
-	listSessions := types.NewString(tmuxCmd.OutBuf().String())
-	splitCnt := 2
-
-	itr := strings.SplitSeq(listSessions.Value(), "\n")
-	for line := range itr {
-		parts := strings.SplitN(line, ":", splitCnt)
-		sessionName, projectPath := types.NewString(parts[0]), types.NewString(parts[1])
-		existingSessions[projectPath] = session.NewSession(sessionName, projectPath)
+	for line := range strings.SplitSeq(tmuxCmd.OutBuf().String(), "\n") {
+		if s, ok := parseSession(line); ok {
+			existingSessions[s.Key()] = s
+		}
 	}
 
 	return existingSessions, nil
 }
 
-func (t *Tmux) CreateAndAttach(ctx context.Context, session *session.Session) error {
-	tmuxCmd := command.NewTmuxCommand(ctx, "new-session", "-s", session.Name.Value(), "-c", session.ProjectPath.Value())
-	return tmuxCmd.Run()
+// parseSession reads a line printed by GatherExistingSessions.
+func parseSession(line string) (*session.Session, bool) {
+	splitCnt := 6
+	parts := strings.SplitN(line, ":", splitCnt)
+	if len(parts) != splitCnt {
+		return nil, false
+	}
+	// The workspace name is followed by ':' and the path.
+	n, err := strconv.Atoi(parts[4])
+	if err != nil || n < 0 || n >= len(parts[5]) || parts[5][n] != ':' {
+		return nil, false
+	}
+	s := session.NewSession(types.NewString(parts[0]), types.NewString(parts[5][n+1:]))
+	s.Foreign = len(parts[1]) == 0
+	s.Scratch = len(parts[2]) > 0
+	// session_attached counts the clients attached.
+	s.Attached = parts[3] != "0"
+	s.Workspace = parts[5][:n]
+	return s, true
+}
+
+// Create starts session in the background. It is tagged with a user option,
+// and so are scratch and workspace sessions, so they can be told apart when
+// sessions are gathered again.
+func (t *Tmux) Create(ctx context.Context, session *session.Session) error {
+	args := []string{"new-session", "-ds", session.Name.Value(), "-c", session.ProjectPath.Value()}
+	tmuxCmd := command.NewTmuxCommand(ctx, append(args, environFlags(session.Environ)...)...)
+	if err := tmuxCmd.Run(); err != nil {
+		return err
+	}
+	tmuxCmd = command.NewTmuxCommand(ctx, "set-option", "-t", session.Name.Value(), sessionizerOption, "1")
+	if err := tmuxCmd.Run(); err != nil {
+		return err
+	}
+
+	if session.Scratch {
+		tmuxCmd := command.NewTmuxCommand(ctx, "set-option", "-t", session.Name.Value(), scratchOption, "1")
+		if err := tmuxCmd.Run(); err != nil {
+			return err
+		}
+	}
+	if len(session.Workspace) > 0 {
+		tmuxCmd := command.NewTmuxCommand(ctx, "set-option", "-t", session.Name.Value(), workspaceOption, session.Workspace)
+		return tmuxCmd.Run()
+	}
+	return nil
 }
 
 func (t *Tmux) Attach(ctx context.Context, session *session.Session) error {
@@ -58,15 +110,55 @@ func (t *Tmux) SwitchClient(ctx context.Context, switchTo *session.Session) erro
 	return tmuxCmd.Run()
 }
 
-func (t *Tmux) SwitchToNewClient(ctx context.Context, switchTo *session.Session) error {
-	tmuxCmd := command.NewTmuxCommand(ctx, "new-session", "-ds", switchTo.Name.Value(), "-c", switchTo.ProjectPath.Value())
+// NewWindow adds a window named name to session in the background.
+func (t *Tmux) NewWindow(ctx context.Context, session *session.Session, name string, path string, environ []string) error {
+	args := []string{"new-window", "-d", "-t", session.Name.Value() + ":", "-n", name, "-c", path}
+	tmuxCmd := command.NewTmuxCommand(ctx, append(args, environFlags(environ)...)...)
+	return tmuxCmd.Run()
+}
 
-	err := tmuxCmd.Run()
-	if err != nil {
-		return err
+// NewCurrentWindow adds a window named name to the current session and
+// selects it.
+func (t *Tmux) NewCurrentWindow(ctx context.Context, name string, path string, environ []string) error {
+	args := []string{"new-window", "-n", name, "-c", path}
+	tmuxCmd := command.NewTmuxCommand(ctx, append(args, environFlags(environ)...)...)
+	return tmuxCmd.Run()
+}
+
+// FindCurrentWindow returns the id of the window of the current session whose
+// active pane is in path, if there is one.
+func (t *Tmux) FindCurrentWindow(ctx context.Context, path string) (string, bool, error) {
+	format := "#{window_id}:#{pane_current_path}"
+	tmuxCmd := command.NewTmuxCommand(ctx, "list-windows", "-F", format)
+	if err := tmuxCmd.Run(); err != nil {
+		return "", false, fmt.Errorf("failed to list windows with `tmux list-windows -F '%s'`: %w", format, err)
+	}
+
+	for line := range strings.SplitSeq(strings.TrimSpace(tmuxCmd.OutBuf().String()), "\n") {
+		// Window ids never contain ':', so only the path may hold one.
+		id, windowPath, found := strings.Cut(line, ":")
+		if found && windowPath == path {
+			return id, true, nil
+		}
 	}
+	return "", false, nil
+}
 
-	return t.SwitchClient(ctx, switchTo)
+// SelectWindow makes the window id the current one.
+func (t *Tmux) SelectWindow(ctx context.Context, id string) error {
+	tmuxCmd := command.NewTmuxCommand(ctx, "select-window", "-t", id)
+	return tmuxCmd.Run()
+}
+
+// RenameWindow renames the current window of session.
+func (t *Tmux) RenameWindow(ctx context.Context, session *session.Session, name string) error {
+	tmuxCmd := command.NewTmuxCommand(ctx, "rename-window", "-t", session.Name.Value()+":", name)
+	return tmuxCmd.Run()
+}
+
+func (t *Tmux) Rename(ctx context.Context, session *session.Session, name types.String) error {
+	tmuxCmd := command.NewTmuxCommand(ctx, "rename-session", "-t", session.Name.Value(), name.Value())
+	return tmuxCmd.Run()
 }
 
 func (t *Tmux) Delete(ctx context.Context, session *session.Session) error {
@@ -74,6 +166,24 @@ func (t *Tmux) Delete(ctx context.Context, session *session.Session) error {
 	return tmuxCmd.Run()
 }
 
+// environFlags passes KEY=VALUE pairs to new-session and new-window, so every
+// pane starts with them and not only those opened after set-environment.
+func environFlags(environ []string) []string {
+	flags := make([]string, 0, 2*len(environ))
+	for _, e := range environ {
+		flags = append(flags, "-e", e)
+	}
+	return flags
+}
+
+// Ping checks that a tmux server answers, without printing anything.
+func (t *Tmux) Ping(ctx context.Context) error {
+	if _, err := command.Output(ctx, "tmux", "list-sessions", "-F", "#{session_name}"); err != nil {
+		return fmt.Errorf("failed to reach the tmux server:%w", err)
+	}
+	return nil
+}
+
 func (t *Tmux) IsInSession() bool {
 	return len(os.Getenv(tmux)) > 0
 }
@@ -87,3 +197,41 @@ func (t *Tmux) HasSession(ctx context.Context, session *session.Session) bool {
 
 	return tmuxCmd.Run() == nil
 }
+
+// Popup is where display-popup shows a popup and how large it is, in the
+// units tmux takes: cells, a percentage such as 80%, or for X and Y a
+// position such as C for centered.
+type Popup struct {
+	// Client is the client to show the popup on; tmux picks one when empty.
+	Client string
+	Width  string
+	Height string
+	X      string
+	Y      string
+}
+
+// DisplayPopup runs args in a popup, closed as soon as they exit.
+func (t *Tmux) DisplayPopup(ctx context.Context, popup Popup, args []string) error {
+	tmuxArgs := []string{"display-popup", "-E", "-w", popup.Width, "-h", popup.Height, "-x", popup.X, "-y", popup.Y}
+	if len(popup.Client) > 0 {
+		tmuxArgs = append(tmuxArgs, "-c", popup.Client)
+	}
+	tmuxCmd := command.NewTmuxCommand(ctx, append(tmuxArgs, ShellJoin(args))...)
+	return tmuxCmd.Run()
+}
+
+// ShellJoin quotes every argument for sh and joins them, so tmux runs exactly
+// args whatever they contain.
+func ShellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, a := range args {
+		quoted = append(quoted, "'"+strings.ReplaceAll(a, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Quote quotes s for a tmux config file, where double quoted strings still
+// expand $VARIABLES and escapes.
+func Quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s) + `"`
+}
@@ -0,0 +1,70 @@
+package tmux
+
+import (
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/session"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		line   string
+		want   *session.Session
+		wantOK bool
+	}{
+		{
+			name:   "project session",
+			line:   "app:1::1:0::/src/app",
+			want:   &session.Session{Name: types.NewString("app"), ProjectPath: types.NewString("/src/app"), Attached: true},
+			wantOK: true,
+		},
+		{
+			name:   "foreign session",
+			line:   "notes:::0:0::/src/app",
+			want:   &session.Session{Name: types.NewString("notes"), ProjectPath: types.NewString("/src/app"), Foreign: true},
+			wantOK: true,
+		},
+		{
+			name:   "scratch session",
+			line:   "scratch-1:1:1:0:0::/tmp/scratch-1",
+			want:   &session.Session{Name: types.NewString("scratch-1"), ProjectPath: types.NewString("/tmp/scratch-1"), Scratch: true},
+			wantOK: true,
+		},
+		{
+			name:   "workspace and path holding ':'",
+			line:   "micro:1::0:6:mi:cro:/src/a:b",
+			want:   &session.Session{Name: types.NewString("micro"), ProjectPath: types.NewString("/src/a:b"), Workspace: "mi:cro"},
+			wantOK: true,
+		},
+		{
+			name: "empty line",
+			line: "",
+		},
+		{
+			name: "workspace length beyond the line",
+			line: "micro:1::0:9:micro:/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := parseSession(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parseSession(%q) ok = %v, want %v", tt.line, ok, tt.wantOK)
+			}
+			opt := cmp.Comparer(func(a, b types.String) bool {
+				return a.Value() == b.Value()
+			})
+			if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+				t.Errorf("parseSession(%q) mismatch (-want +got):\n%s", tt.line, diff)
+			}
+		})
+	}
+}
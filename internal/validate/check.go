@@ -0,0 +1,341 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/hook"
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single problem of the config file or a file it includes.
+// Line and Column are 1-based, Column counting bytes like most editors'
+// "goto" do.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.file, p.line, p.column)
+}
+
+// HasErrors tells whether any of diagnostics is an error rather than a warning.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// entry is a comma separated field of a value, trimmed, with its column.
+type entry struct {
+	text   string
+	column int
+}
+
+type position struct {
+	file   string
+	line   int
+	column int
+}
+
+type checker struct {
+	filer *io.Filer
+	// file is the file of the line being checked.
+	file        string
+	diagnostics []Diagnostic
+	roots       map[string]position
+	rootOrder   []string
+}
+
+// CheckConfig reads the whole config file, and the files it includes, and
+// reports every problem it finds, unlike ValidateConfig and
+// ConfigParser.ReadConfig which stop at the first one or silently skip what
+// they cannot use. The error is only about reading the files.
+func CheckConfig(filer *io.Filer, configFileAbs string) ([]Diagnostic, error) {
+	lines, err := io.ReadConfigLines(filer, configFileAbs)
+	if err != nil {
+		return nil, fmt.Errorf("config file could not be read:%w", err)
+	}
+
+	c := &checker{
+		filer:       filer,
+		diagnostics: []Diagnostic{},
+		roots:       make(map[string]position),
+	}
+
+	// Like ValidateConfig, the config file itself must set some key, any
+	// key; included files and overlays are fragments.
+	if !slices.ContainsFunc(lines, func(l io.ConfigLine) bool { return l.File == configFileAbs && io.HasKey(l.Text) }) {
+		c.file = configFileAbs
+		c.report(1, 1, SeverityError, "config file has no settings, you need to initialize config file with 'tmux-sessionizer init'")
+	}
+	for _, l := range lines {
+		c.file = l.File
+		c.checkLine(l.Line, l.Text)
+	}
+
+	c.checkNestedRoots()
+	return c.diagnostics, nil
+}
+
+func (c *checker) report(line int, column int, severity Severity, format string, a ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		File:     c.file,
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *checker) checkLine(lineNo int, line string) {
+	// ReadConfig ignores lines it does not know, so blank lines and comments
+	// are fine; anything else unknown is most likely a typo.
+	if trimmed := strings.TrimSpace(line); len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+		return
+	}
+
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		c.report(lineNo, 1, SeverityError, "expected key=value, got %q", line)
+		return
+	}
+	// The value starts right after "key=".
+	offset := len(key) + 2
+
+	switch key + "=" {
+	case io.ConfigPrefix:
+		for _, e := range splitEntries(value, offset) {
+			if len(e.text) > 0 {
+				c.checkRoot(lineNo, e)
+			}
+		}
+	case io.RootPrefix:
+		entries := splitEntries(value, offset)
+		if _, err := io.ParseDepth(entries[0].text); err != nil {
+			c.report(lineNo, entries[0].column, SeverityError, "%v", err)
+			return
+		}
+		for _, e := range entries[1:] {
+			if len(e.text) > 0 {
+				c.checkRoot(lineNo, e)
+			}
+		}
+	case io.ProjectPrefix:
+		for _, e := range splitEntries(value, offset) {
+			if len(e.text) > 0 {
+				c.checkProject(lineNo, e)
+			}
+		}
+	case io.AliasPrefix:
+		path, name, found := strings.Cut(value, ",")
+		if !found || len(path) == 0 || len(name) == 0 {
+			c.report(lineNo, offset, SeverityError, "expected %s<path>,<name>", io.AliasPrefix)
+		}
+	case io.WorktreePrefix:
+		if len(strings.TrimSpace(value)) == 0 {
+			c.report(lineNo, offset, SeverityError, "expected a directory after %s=", key)
+		}
+	case io.ClonePrefix:
+		if len(strings.TrimSpace(value)) == 0 {
+			c.report(lineNo, offset, SeverityWarning, "%s= has no directory, %s is used", key, io.DefaultCloneRoot)
+		}
+	case io.WorkspacePrefix:
+		c.checkWorkspace(lineNo, value, offset)
+	case io.EnvPrefix:
+		scope, _, err := io.SplitEnv(value)
+		if err != nil {
+			c.report(lineNo, offset, SeverityError, "%v", err)
+		} else if len(strings.TrimSpace(scope)) > 0 {
+			c.checkDir(lineNo, entry{text: strings.TrimSpace(scope), column: offset + leadingSpaces(scope)}, "env scope")
+		}
+	case io.HookPrefix:
+		rawEvent, script, _ := strings.Cut(value, ",")
+		if _, err := hook.ParseEvent(strings.TrimSpace(rawEvent)); err != nil {
+			c.report(lineNo, offset, SeverityError, "%v", err)
+		} else if len(strings.TrimSpace(script)) == 0 {
+			c.report(lineNo, offset+len(rawEvent), SeverityWarning, "%s hook has no command", strings.TrimSpace(rawEvent))
+		}
+	case io.LabelPrefix:
+		if _, err := io.ParseLabel(value); err != nil {
+			c.report(lineNo, offset, SeverityError, "%v", err)
+		}
+	case io.HookTimeoutPrefix:
+		if _, err := time.ParseDuration(strings.TrimSpace(value)); err != nil {
+			c.report(lineNo, offset, SeverityError, "invalid duration: %v", err)
+		}
+	case io.DiscoveryTimeoutPrefix:
+		if _, err := io.ParseDiscoveryTimeout(value); err != nil {
+			c.report(lineNo, offset, SeverityError, "%v", err)
+		}
+	default:
+		c.report(lineNo, 1, SeverityError, "unknown key %q", key)
+	}
+}
+
+func (c *checker) checkRoot(lineNo int, e entry) {
+	if !c.checkGlob(lineNo, e) {
+		return
+	}
+	roots, err := c.filer.ResolveRoots(e.text)
+	if errors.Is(err, io.ErrUndefinedEnv) {
+		c.report(lineNo, e.column, SeverityError, "%v, it is skipped", err)
+		return
+	} else if err != nil {
+		c.report(lineNo, e.column, SeverityError, "%v", err)
+		return
+	}
+	if io.IsGlob(e.text) && len(roots) == 0 {
+		c.report(lineNo, e.column, SeverityWarning, "glob %q matches no directory", e.text)
+	}
+
+	for _, abs := range roots {
+		c.checkPath(lineNo, e.column, "root", abs)
+		if first, exists := c.roots[abs]; exists {
+			c.report(lineNo, e.column, SeverityError, "duplicate root %s, already listed at %s", abs, first)
+			continue
+		}
+		c.roots[abs] = position{file: c.file, line: lineNo, column: e.column}
+		c.rootOrder = append(c.rootOrder, abs)
+	}
+}
+
+func (c *checker) checkProject(lineNo int, e entry) {
+	if !c.checkGlob(lineNo, e) {
+		return
+	}
+	projects, err := c.filer.ResolveRoots(e.text)
+	if errors.Is(err, io.ErrUndefinedEnv) {
+		c.report(lineNo, e.column, SeverityError, "%v, it is skipped", err)
+		return
+	} else if err != nil {
+		c.report(lineNo, e.column, SeverityError, "%v", err)
+		return
+	}
+	if io.IsGlob(e.text) && len(projects) == 0 {
+		c.report(lineNo, e.column, SeverityWarning, "glob %q matches no directory", e.text)
+	}
+	for _, abs := range projects {
+		c.checkPath(lineNo, e.column, "project", abs)
+	}
+}
+
+// checkGlob reports a malformed pattern. Entries without metacharacters are
+// plain paths and always fine.
+func (c *checker) checkGlob(lineNo int, e entry) bool {
+	if !io.IsGlob(e.text) {
+		return true
+	}
+	if _, err := filepath.Match(e.text, ""); errors.Is(err, filepath.ErrBadPattern) {
+		c.report(lineNo, e.column, SeverityError, "invalid glob %q", e.text)
+		return false
+	}
+	return true
+}
+
+func (c *checker) checkWorkspace(lineNo int, value string, offset int) {
+	entries := splitEntries(value, offset)
+	if len(entries) == 0 || len(entries[0].text) == 0 {
+		c.report(lineNo, offset, SeverityError, "expected %s<name>,<path>,<path>...", io.WorkspacePrefix)
+		return
+	}
+
+	projects := 0
+	for _, e := range entries[1:] {
+		if len(e.text) == 0 {
+			continue
+		}
+		projects++
+		c.checkDir(lineNo, e, "workspace project")
+	}
+	if projects == 0 {
+		c.report(lineNo, offset, SeverityWarning, "workspace %s has no projects", entries[0].text)
+	}
+}
+
+// checkDir reports a directory that ReadConfig would skip or fail on.
+func (c *checker) checkDir(lineNo int, e entry, what string) {
+	path, err := c.filer.ExpandTildeAsHomeDir(e.text)
+	if err != nil {
+		c.report(lineNo, e.column, SeverityError, "%v", err)
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		c.report(lineNo, e.column, SeverityError, "%v", err)
+		return
+	}
+	c.checkPath(lineNo, e.column, what, abs)
+}
+
+func (c *checker) checkPath(lineNo int, column int, what string, abs string) {
+	info, err := os.Stat(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		c.report(lineNo, column, SeverityWarning, "%s %s does not exist, it is skipped", what, abs)
+	case err != nil:
+		c.report(lineNo, column, SeverityError, "%s %s is unreadable: %v", what, abs, err)
+	case !info.IsDir():
+		c.report(lineNo, column, SeverityError, "%s %s is not a directory", what, abs)
+	default:
+		if _, err := os.ReadDir(abs); err != nil {
+			c.report(lineNo, column, SeverityWarning, "%s %s is unreadable, it is skipped: %v", what, abs, err)
+		}
+	}
+}
+
+// checkNestedRoots warns about a root inside another one: the inner root is
+// then a project of the outer root as well as a root of its own.
+func (c *checker) checkNestedRoots() {
+	for _, inner := range c.rootOrder {
+		for _, outer := range c.rootOrder {
+			if inner == outer || !strings.HasPrefix(inner, outer+string(filepath.Separator)) {
+				continue
+			}
+			at, from := c.roots[inner], c.roots[outer]
+			c.file = at.file
+			c.report(at.line, at.column, SeverityWarning, "root %s is nested inside root %s listed at %s", inner, outer, from)
+		}
+	}
+}
+
+// splitEntries splits value on commas. offset is the column value starts at.
+func splitEntries(value string, offset int) []entry {
+	entries := []entry{}
+	column := offset
+	for _, field := range strings.Split(value, ",") {
+		entries = append(entries, entry{
+			text:   strings.TrimSpace(field),
+			column: column + leadingSpaces(field),
+		})
+		column += len(field) + 1
+	}
+	return entries
+}
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " \t"))
+}
@@ -0,0 +1,109 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src, work := filepath.Join(dir, "src"), filepath.Join(dir, "src", "work")
+	if err := os.MkdirAll(work, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Diagnostic
+	}{
+		{
+			name:    "valid config",
+			content: io.ConfigPrefix + src + "\n\n# comment\nhook=post-create,make\nhook-timeout=1m\ndiscovery-timeout=10s\nenv=" + src + ",A=b\nlabel=root,color,running\n",
+			want:    []Diagnostic{},
+		},
+		{
+			name:    "any key may come first",
+			content: "alias=" + src + ",src\n" + io.ConfigPrefix + src + "\n",
+			want:    []Diagnostic{},
+		},
+		{
+			name:    "no settings",
+			content: "# nothing yet\n",
+			want: []Diagnostic{
+				{Line: 1, Column: 1, Severity: SeverityError, Message: "config file has no settings, you need to initialize config file with 'tmux-sessionizer init'"},
+			},
+		},
+		{
+			name:    "roots",
+			content: io.ConfigPrefix + src + ", " + missing + "," + file + "\n" + io.ConfigPrefix + work + "," + src + ",[\n",
+			want: []Diagnostic{
+				{Line: 1, Column: 11 + len(src), Severity: SeverityWarning, Message: "root " + missing + " does not exist, it is skipped"},
+				{Line: 1, Column: 12 + len(src) + len(missing), Severity: SeverityError, Message: "root " + file + " is not a directory"},
+				{Line: 2, Column: 10 + len(work), Severity: SeverityError, Message: "duplicate root " + src + ", already listed at $CONFIG:1:9"},
+				{Line: 2, Column: 11 + len(work) + len(src), Severity: SeverityError, Message: `invalid glob "["`},
+				{Line: 2, Column: 9, Severity: SeverityWarning, Message: "root " + work + " is nested inside root " + src + " listed at $CONFIG:1:9"},
+			},
+		},
+		{
+			name:    "root and project entries",
+			content: io.ConfigPrefix + "\n" + io.RootPrefix + "2," + src + "\n" + io.RootPrefix + "0," + work + "\n" + io.ProjectPrefix + work + "," + file + "\n",
+			want: []Diagnostic{
+				{Line: 3, Column: 6, Severity: SeverityError, Message: `"0":root depth must be a positive number`},
+				{Line: 4, Column: 10 + len(work), Severity: SeverityError, Message: "project " + file + " is not a directory"},
+			},
+		},
+		{
+			name:    "undefined environment variable",
+			content: io.ConfigPrefix + src + ",$TMUX_SESSIONIZER_SURELY_UNDEFINED/src\n",
+			want: []Diagnostic{
+				{Line: 1, Column: 10 + len(src), Severity: SeverityError, Message: "$TMUX_SESSIONIZER_SURELY_UNDEFINED in $TMUX_SESSIONIZER_SURELY_UNDEFINED/src:undefined environment variable, it is skipped"},
+			},
+		},
+		{
+			name:    "unknown keys and bad values",
+			content: io.ConfigPrefix + "\nworkspaces=a," + src + "\nnot a key\nhook=post-kill,true\nhook-timeout=soon\nenv=NOT VALID\nalias=" + src + "\nlabel=home,bold\ndiscovery-timeout=0s\n",
+			want: []Diagnostic{
+				{Line: 2, Column: 1, Severity: SeverityError, Message: `unknown key "workspaces"`},
+				{Line: 3, Column: 1, Severity: SeverityError, Message: `expected key=value, got "not a key"`},
+				{Line: 4, Column: 6, Severity: SeverityError, Message: `"post-kill":unknown hook event`},
+				{Line: 5, Column: 14, Severity: SeverityError, Message: `invalid duration: time: invalid duration "soon"`},
+				{Line: 6, Column: 5, Severity: SeverityError, Message: `"NOT VALID":invalid environment variable`},
+				{Line: 7, Column: 7, Severity: SeverityError, Message: "expected alias=<path>,<name>"},
+				{Line: 8, Column: 7, Severity: SeverityError, Message: `option "bold", want color or running:invalid label`},
+				{Line: 9, Column: 19, Severity: SeverityError, Message: `"0s":discovery timeout must be a positive duration`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFileAbs := writeConfig(t, tt.content)
+			got, err := CheckConfig(io.NewFiler(), configFileAbs)
+			if err != nil {
+				t.Fatalf("CheckConfig() error = %v", err)
+			}
+			for i := range tt.want {
+				tt.want[i].File = configFileAbs
+				tt.want[i].Message = strings.ReplaceAll(tt.want[i].Message, "$CONFIG", configFileAbs)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("CheckConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,30 +1,27 @@
 package validate
 
 import (
-	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/TlexCypher/my-tmux-sessionizer/internal/io"
 )
 
+// ValidateConfig tells whether configFileAbs is a config file of
+// tmux-sessionizer at all, which takes a line setting any of its keys.
+// Which one comes first does not matter, so a file may start with include=.
 func ValidateConfig(configFileAbs string) error {
-	f, err := os.Open(configFileAbs)
+	b, err := os.ReadFile(configFileAbs)
 	if err != nil {
 		// Without a readable file the content checks below are meaningless.
 		return fmt.Errorf("config file could not be opened:%w", err)
 	}
-	defer f.Close()
 
-	var errs []error
-
-	buf := make([]byte, len(io.ConfigPrefix))
-	if _, err := f.ReadAt(buf, 0); err != nil {
-		errs = append(errs, fmt.Errorf("failed to get first %d bytes:%w", len(io.ConfigPrefix), err))
-	}
-	if string(buf) != io.ConfigPrefix {
-		errs = append(errs, fmt.Errorf("config prefix must be %s, you need to initialize config file with 'tmux-sessionizer init'", io.ConfigPrefix))
+	for line := range strings.SplitSeq(string(b), "\n") {
+		if io.HasKey(line) {
+			return nil
+		}
 	}
-
-	return errors.Join(errs...)
+	return fmt.Errorf("config file has no settings, you need to initialize config file with 'tmux-sessionizer init'")
 }
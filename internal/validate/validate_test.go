@@ -38,6 +38,16 @@ func TestValidateConfig_PrefixOnly_ReturnsNil(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_IncludeFirst_ReturnsNil(t *testing.T) {
+	t.Parallel()
+
+	configFileAbs := writeConfig(t, "include = [~/dotfiles/tmux-sessionizer]\n"+io.ConfigPrefix+"/home/user/src\n")
+
+	if err := ValidateConfig(configFileAbs); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
 func TestValidateConfig_MissingFile_ReturnsError(t *testing.T) {
 	t.Parallel()
 
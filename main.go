@@ -1,8 +1,6 @@
 package main
 
 import (
-	"flag"
-	"fmt"
 	"log/slog"
 	"os"
 	"runtime/debug"
@@ -26,16 +24,7 @@ func getVersion() string {
 }
 
 func main() {
-	var showVersion bool
-	flag.BoolVar(&showVersion, "version", false, "Print the version and exit")
-	flag.Parse()
-
-	if showVersion {
-		fmt.Println(getVersion())
-		return
-	}
-
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
-	os.Exit(cmd.Core())
+	os.Exit(cmd.Core(getVersion()))
 }
@@ -2,6 +2,8 @@ package testutils
 
 import (
 	"os"
+	"os/exec"
+	"testing"
 
 	"github.com/samber/lo"
 )
@@ -9,3 +11,36 @@ import (
 func GetUserHomeDir() string {
 	return lo.Must(os.UserHomeDir())
 }
+
+// Git runs git in dir with a fixed identity, so commits work on machines
+// without a global git config. It skips the test when git is not installed.
+func Git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	cmd := exec.CommandContext(t.Context(), "git", append([]string{
+		"-C", dir,
+		"-c", "user.name=tmux-sessionizer",
+		"-c", "user.email=tmux-sessionizer@example.com",
+		"-c", "init.defaultBranch=main",
+	}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// InitGitRepo creates a repository with a single empty commit at dir.
+func InitGitRepo(t *testing.T, dir string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	Git(t, dir, "init", "-q")
+	Git(t, dir, "commit", "-q", "--allow-empty", "-m", "init")
+}